@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// User aliases are kept in a text file, one per line, as "name = expansion", e.g.
+//
+//	scout = explore $1; catch --all
+//
+// The expansion is one or more commands separated by ";". $1 to $9 stand for the arguments the
+// alias is called with and $@ for all of them. An expansion without any of those gets the
+// arguments added to its end, so "ex = explore" makes "ex canalave-city-area" work.
+// Blank lines and lines starting with # are skipped.
+
+// aliasFileHeader starts the alias file whenever the alias and unalias commands save it.
+const aliasFileHeader = "# Pokedex aliases, one per line as \"name = commands\". Managed by the alias and unalias commands.\n"
+
+// maxAliasArgs is the highest $N placeholder an expansion can use.
+const maxAliasArgs = 9
+
+// loadAliases reads the alias file at path. A missing file means there are no aliases yet.
+func loadAliases(path string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return aliases, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, expansion, ok := strings.Cut(line, "=")
+		name, expansion = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(expansion)
+		if !ok {
+			return nil, fmt.Errorf("%s line %d: expected \"name = commands\"", path, lineNumber)
+		}
+		// only the syntax is checked, so that an alias file written by hand or by an older version
+		// still loads, runAlias refuses what it can't run when it is run
+		if err := checkAlias(name, expansion); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, lineNumber, err)
+		}
+		aliases[name] = expansion
+	}
+	return aliases, scanner.Err()
+}
+
+// saveAliases writes aliases to the file at path, in alphabetical order.
+func saveAliases(path string, aliases map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(aliasFileHeader)
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s = %s\n", name, aliases[name])
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// checkAlias reports why name and expansion can't be used as an alias, or nil if they can.
+func checkAlias(name, expansion string) error {
+	if name == "" || strings.ContainsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || strings.ContainsRune("=;'\"\\", r) }) {
+		return fmt.Errorf("%q can't be an alias name, use a single word without quotes, = or ;", name)
+	}
+	if command, ok := lookupCommand(name); ok {
+		if command.name == name {
+			return fmt.Errorf("%s is a built-in command", name)
+		}
+		return fmt.Errorf("%s is a built-in alias for %s", name, command.name)
+	}
+	commands := splitCommands(expansion)
+	if len(commands) == 0 {
+		return fmt.Errorf("the alias %s needs at least one command", name)
+	}
+	for _, command := range commands {
+		words, err := cleanInput(command)
+		if err != nil {
+			return fmt.Errorf("the alias %s: %w", name, err)
+		}
+		if len(words) == 0 {
+			return fmt.Errorf("the alias %s has an empty command between two \";\"", name)
+		}
+	}
+	return nil
+}
+
+// checkNewAlias is checkAlias for an alias being defined next to the user's existing aliases.
+// It also makes sure the expansion only uses built-in commands: neither the user's aliases,
+// which runAlias refuses to run, nor commands that don't exist.
+func checkNewAlias(name, expansion string, aliases map[string]string) error {
+	if err := checkAlias(name, expansion); err != nil {
+		return err
+	}
+	for _, command := range splitCommands(expansion) {
+		words, _ := cleanInput(command)
+		if strings.HasPrefix(words[0], "$") { // the command comes from the alias's arguments
+			continue
+		}
+		if _, ok := aliases[words[0]]; ok || words[0] == name {
+			return fmt.Errorf("the alias %s uses the alias %s, but aliases can only use built-in commands", name, words[0])
+		}
+		if _, ok := lookupCommand(words[0]); !ok {
+			return &usageError{command: GetCommands()["alias"], problem: fmt.Sprintf("the alias %s uses %s, which is not a command", name, words[0])}
+		}
+	}
+	return nil
+}
+
+// splitCommands splits text into the commands separated by ";", leaving out ones that are
+// only whitespace. A ";" inside quotes or after a backslash doesn't separate commands.
+func splitCommands(text string) []string {
+	var commands []string
+	start := 0
+	var quote rune
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			}
+		case quote == '"':
+			if r == '\\' {
+				i++
+			} else if r == '"' {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '\\':
+			i++
+		case r == ';':
+			commands = append(commands, string(runes[start:i]))
+			start = i + 1
+		}
+	}
+	commands = append(commands, string(runes[start:]))
+	return slices.DeleteFunc(commands, func(c string) bool { return strings.TrimSpace(c) == "" })
+}
+
+// expandAlias turns a call of the alias name, with args, into the commands it stands for.
+func expandAlias(name, expansion string, args []string) ([][]string, error) {
+	// the arguments replace the placeholders in one pass, so arguments that look like
+	// placeholders are left alone
+	pairs := []string{"$@", strings.Join(args, " ")}
+	for n := 1; n <= maxAliasArgs; n++ {
+		if n <= len(args) {
+			pairs = append(pairs, "$"+strconv.Itoa(n), args[n-1])
+		}
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	var commands [][]string
+	used := 0 // the highest $N in the expansion
+	usesAll := false
+	for _, command := range splitCommands(expansion) {
+		words, err := cleanInput(command)
+		if err != nil {
+			return nil, fmt.Errorf("the alias %s: %w", name, err)
+		}
+		var expanded []string
+		for _, word := range words {
+			usesAll = usesAll || strings.Contains(word, "$@")
+			for n := 1; n <= maxAliasArgs; n++ {
+				if strings.Contains(word, "$"+strconv.Itoa(n)) {
+					used = max(used, n)
+				}
+			}
+			if word == "$@" {
+				expanded = append(expanded, args...)
+			} else {
+				expanded = append(expanded, replacer.Replace(word))
+			}
+		}
+		commands = append(commands, expanded)
+	}
+
+	switch {
+	case used == 0 && !usesAll:
+		last := len(commands) - 1
+		commands[last] = append(commands[last], args...)
+	case len(args) < used:
+		return nil, fmt.Errorf("%s needs %d argument(s), it stands for %q", name, used, expansion)
+	case len(args) > used && !usesAll:
+		return nil, fmt.Errorf("%s takes %d argument(s), it stands for %q", name, used, expansion)
+	}
+	return commands, nil
+}
+
+// runAlias runs the commands the alias name stands for, stopping at the first one that fails,
+// and returns the exit code of the last one run. Aliases can't call other aliases, so they
+// can't loop forever.
+func runAlias(userConfig *config, name string, args []string) int {
+	commands, err := expandAlias(name, userConfig.Aliases[name], args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_USAGE
+	}
+	for _, words := range commands {
+		if len(words) == 0 { // a command that was only $@, called without arguments
+			continue
+		}
+		if _, ok := userConfig.Aliases[words[0]]; ok {
+			fmt.Fprintf(os.Stderr, "the alias %s uses the alias %s, but aliases can only use built-in commands\n", name, words[0])
+			return EXIT_USAGE
+		}
+		if code := runCommand(userConfig, words); code != EXIT_OK {
+			return code
+		}
+	}
+	return EXIT_OK
+}
+
+// defaultAliasFile is where user aliases are kept unless -alias-file says otherwise: the pokedex
+// directory in $XDG_CONFIG_HOME, or ~/.config if that isn't set.
+func defaultAliasFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pokedex", "aliases")
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// argSpec declares a positional argument of a command.
+type argSpec struct {
+	name        string
+	description string
+	optional    bool
+	choices     []string // the only values allowed, if set
+	// identifier marks arguments that name something in PokeAPI, or one of choices. They are
+	// lowercased, since that's how PokeAPI spells names. Other arguments keep their case.
+	identifier bool
+}
+
+// flagKind is the type of value a flag takes.
+type flagKind int
+
+const (
+	flagBool flagKind = iota // no value, e.g. "--stale"
+	flagInt
+	flagString
+)
+
+// flagSpec declares a flag of a command.
+type flagSpec struct {
+	name        string // long name, used as "--name"
+	short       string // optional one letter name, used as "-s"
+	kind        flagKind
+	value       string // default value
+	description string
+}
+
+// commandInput is what the user passed to a command, checked against its declared arguments
+// and flags.
+type commandInput struct {
+	args  map[string]string
+	flags map[string]string
+}
+
+// Arg returns the positional argument called name, or "" if it is optional and wasn't given.
+func (in commandInput) Arg(name string) string {
+	return in.args[name]
+}
+
+// Bool returns whether the bool flag called name was set.
+func (in commandInput) Bool(name string) bool {
+	return in.flags[name] == "true"
+}
+
+// Int returns the value of the int flag called name. parse has already checked it is a number.
+func (in commandInput) Int(name string) int {
+	n, _ := strconv.Atoi(in.flags[name])
+	return n
+}
+
+// String returns the value of the string flag called name.
+func (in commandInput) String(name string) string {
+	return in.flags[name]
+}
+
+// usageError is returned for arguments or flags that don't match what a command declares.
+type usageError struct {
+	command cliCommand
+	problem string
+}
+
+func (e *usageError) Error() string {
+	return fmt.Sprintf("%s: %s\nUsage: %s\nRun \"help %s\" for more.", e.command.name, e.problem, e.command.usage(), e.command.name)
+}
+
+// isUsageError reports whether err came from a command being called the wrong way.
+func isUsageError(err error) bool {
+	var u *usageError
+	return errors.As(err, &u)
+}
+
+// parse checks words, the arguments after the command name, against the command's declared
+// arguments and flags. Flags can go anywhere, as "--name value", "--name=value" or "-s value",
+// and "--" ends them.
+func (c cliCommand) parse(words []string) (commandInput, error) {
+	in := commandInput{args: make(map[string]string), flags: make(map[string]string)}
+	for _, f := range c.flags {
+		if f.value != "" {
+			in.flags[f.name] = f.value
+		}
+	}
+	fail := func(format string, a ...any) (commandInput, error) {
+		return commandInput{}, &usageError{command: c, problem: fmt.Sprintf(format, a...)}
+	}
+
+	var positional []string
+	flagsDone := false
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if flagsDone || !isFlag(word) {
+			positional = append(positional, word)
+			continue
+		}
+		if word == "--" {
+			flagsDone = true
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		f, ok := c.flag(name)
+		if !ok {
+			return fail("unknown flag %s", word)
+		}
+		switch {
+		case f.kind == flagBool:
+			set := true
+			if hasValue {
+				var err error
+				if set, err = strconv.ParseBool(value); err != nil {
+					return fail("%s is a switch, it doesn't take a value", word)
+				}
+			}
+			value = strconv.FormatBool(set)
+		case !hasValue:
+			if i+1 == len(words) {
+				return fail("--%s needs a value", f.name)
+			}
+			i++
+			value = words[i]
+		}
+		if f.kind == flagInt {
+			if _, err := strconv.Atoi(value); err != nil {
+				return fail("--%s must be a whole number, not %q", f.name, value)
+			}
+		}
+		in.flags[f.name] = value
+	}
+
+	if len(positional) > len(c.args) {
+		return fail("too many arguments")
+	}
+	for i, a := range c.args {
+		if i >= len(positional) {
+			if !a.optional {
+				return fail("missing <%s>", a.name)
+			}
+			continue
+		}
+		if a.identifier {
+			positional[i] = strings.ToLower(positional[i])
+		}
+		if len(a.choices) > 0 && !slices.Contains(a.choices, positional[i]) {
+			return fail("<%s> must be one of %s, not %q", a.name, strings.Join(a.choices, ", "), positional[i])
+		}
+		in.args[a.name] = positional[i]
+	}
+	return in, nil
+}
+
+// isFlag reports whether word looks like a flag rather than an argument. Negative numbers are arguments.
+func isFlag(word string) bool {
+	if len(word) < 2 || word[0] != '-' {
+		return false
+	}
+	_, err := strconv.Atoi(word)
+	return err != nil
+}
+
+// flag finds a declared flag by its long or short name.
+func (c cliCommand) flag(name string) (flagSpec, bool) {
+	for _, f := range c.flags {
+		if f.name == name || (f.short != "" && f.short == name) {
+			return f, true
+		}
+	}
+	return flagSpec{}, false
+}
+
+// usage is the one line summary of how to call the command, e.g. "cache <subcommand> [key]".
+func (c cliCommand) usage() string {
+	parts := []string{c.name}
+	for _, a := range c.args {
+		if a.optional {
+			parts = append(parts, "["+a.name+"]")
+		} else {
+			parts = append(parts, "<"+a.name+">")
+		}
+	}
+	if len(c.flags) > 0 {
+		parts = append(parts, "[flags]")
+	}
+	return strings.Join(parts, " ")
+}
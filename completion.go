@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/output"
+)
+
+// complete offers tab completions for the word being typed, the last of words.
+// The first word is completed with command names. After that it depends on the command: area
+// names from the map pages seen so far for explore, the Pokemon from the last explore for catch,
+// caught Pokemon for inspect, caught and last explored Pokemon for sprite, and the user's aliases for alias and unalias.
+func (c *config) complete(words []string) []string {
+	if len(words) == 1 {
+		return c.commandNames()
+	}
+
+	switch words[len(words)-2] {
+	case "--output", "-o":
+		var formats []string
+		for _, f := range output.Formats {
+			formats = append(formats, string(f))
+		}
+		return formats
+	}
+	command, ok := lookupCommand(words[0])
+	if !ok || (len(words) > 2 && command.name != "cache") {
+		return nil
+	}
+	switch command.name {
+	case "help":
+		return c.commandNames()
+	case "alias", "unalias":
+		var aliases []string
+		for name := range c.Aliases {
+			aliases = append(aliases, name)
+		}
+		return aliases
+	case "explore":
+		return c.SeenAreas
+	case "catch":
+		return c.LastEncounters
+	case "inspect", "sprite":
+		var caught []string
+		for name := range c.Pokedex {
+			caught = append(caught, name)
+		}
+		if command.name == "sprite" {
+			caught = append(caught, c.LastEncounters...)
+		}
+		return caught
+	case "cache":
+		if len(words) == 2 {
+			return []string{"stats", "list", "clear", "evict"}
+		}
+		if len(words) == 3 && words[1] == "evict" {
+			var keys []string
+			for _, e := range c.LocationCache.List() {
+				keys = append(keys, e.Key)
+			}
+			return keys
+		}
+	}
+	return nil
+}
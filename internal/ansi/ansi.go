@@ -0,0 +1,316 @@
+// Package ansi colors text for terminals with ANSI escape codes, and decides whether a terminal
+// should get colors at all.
+package ansi
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode is the user's choice of when to use colors.
+type Mode string
+
+const (
+	ModeAuto   Mode = "auto"   // color when writing to a terminal, unless NO_COLOR is set
+	ModeAlways Mode = "always" // color even when writing to a file or a pipe
+	ModeNever  Mode = "never"
+)
+
+// Enabled reports whether output written to f should be colored in mode.
+// In ModeAuto that is when f is a terminal, the NO_COLOR environment variable is not set to
+// anything (https://no-color.org) and TERM is not "dumb".
+func Enabled(mode Mode, f *os.File, getenv func(string) string) bool {
+	switch mode {
+	case ModeAlways:
+		return true
+	case ModeNever:
+		return false
+	}
+	if getenv("NO_COLOR") != "" || getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// RGB is a 24-bit color.
+type RGB struct {
+	R, G, B uint8
+}
+
+// Palette turns colors into escape codes for one terminal. The zero Palette writes no escape
+// codes at all, so code can color its text unconditionally and let the Palette decide.
+type Palette struct {
+	Enabled   bool
+	TrueColor bool // the terminal shows 24-bit colors, otherwise the nearest of 256 colors is used
+}
+
+// NewPalette returns the Palette for a terminal, using 24-bit colors when COLORTERM says the
+// terminal supports them.
+func NewPalette(enabled bool, getenv func(string) string) Palette {
+	colorterm := getenv("COLORTERM")
+	return Palette{Enabled: enabled, TrueColor: colorterm == "truecolor" || colorterm == "24bit"}
+}
+
+const reset = "\x1b[0m"
+
+// Fg colors the text s with c.
+func (p Palette) Fg(c RGB, s string) string {
+	if !p.Enabled {
+		return s
+	}
+	return p.FgCode(c) + s + reset
+}
+
+// Bg gives the text s the background c, with black or white text on top, whichever is easier to read.
+func (p Palette) Bg(c RGB, s string) string {
+	if !p.Enabled {
+		return s
+	}
+	text := RGB{255, 255, 255}
+	if luminance(c) > 150 {
+		text = RGB{0, 0, 0}
+	}
+	return p.BgCode(c) + p.FgCode(text) + s + reset
+}
+
+// Bold makes s bold.
+func (p Palette) Bold(s string) string {
+	if !p.Enabled {
+		return s
+	}
+	return "\x1b[1m" + s + reset
+}
+
+// Dim makes s fainter than the text around it.
+func (p Palette) Dim(s string) string {
+	if !p.Enabled {
+		return s
+	}
+	return "\x1b[2m" + s + reset
+}
+
+// Reverse swaps the text and background colors of s, e.g. to highlight a selected line.
+func (p Palette) Reverse(s string) string {
+	if !p.Enabled {
+		return s
+	}
+	return "\x1b[7m" + s + reset
+}
+
+// FgCode is the escape code that sets the text color to c, for code that writes its own resets.
+func (p Palette) FgCode(c RGB) string {
+	if p.TrueColor {
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm", To256(c))
+}
+
+// BgCode is the escape code that sets the background color to c.
+func (p Palette) BgCode(c RGB) string {
+	if p.TrueColor {
+		return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("\x1b[48;5;%dm", To256(c))
+}
+
+// Reset is the escape code that goes back to the terminal's own colors.
+func (p Palette) Reset() string {
+	if !p.Enabled {
+		return ""
+	}
+	return reset
+}
+
+// To256 returns the color in the 256-color palette closest to c: one of the 6x6x6 color cube
+// or the 24 grays.
+func To256(c RGB) uint8 {
+	levels := [6]int{0, 95, 135, 175, 215, 255}
+	nearest := func(v uint8) int {
+		best := 0
+		for i, l := range levels {
+			if abs(int(v)-l) < abs(int(v)-levels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := nearest(c.R), nearest(c.G), nearest(c.B)
+	cube := RGB{uint8(levels[r]), uint8(levels[g]), uint8(levels[b])}
+
+	// grays run from 8 to 238 in steps of 10
+	average := (int(c.R) + int(c.G) + int(c.B)) / 3
+	step := min(max((average-8+5)/10, 0), 23)
+	grayLevel := uint8(8 + step*10)
+	gray := RGB{grayLevel, grayLevel, grayLevel}
+
+	if distance(c, gray) < distance(c, cube) {
+		return uint8(232 + step)
+	}
+	return uint8(16 + 36*r + 6*g + b)
+}
+
+func distance(a, b RGB) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+func luminance(c RGB) int {
+	return (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// typeColors are the colors the games use for each Pokemon type.
+var typeColors = map[string]RGB{
+	"normal":   {0xA8, 0xA7, 0x7A},
+	"fire":     {0xEE, 0x81, 0x30},
+	"water":    {0x63, 0x90, 0xF0},
+	"electric": {0xF7, 0xD0, 0x2C},
+	"grass":    {0x7A, 0xC7, 0x4C},
+	"ice":      {0x96, 0xD9, 0xD6},
+	"fighting": {0xC2, 0x2E, 0x28},
+	"poison":   {0xA3, 0x3E, 0xA1},
+	"ground":   {0xE2, 0xBF, 0x65},
+	"flying":   {0xA9, 0x8F, 0xF3},
+	"psychic":  {0xF9, 0x55, 0x87},
+	"bug":      {0xA6, 0xB9, 0x1A},
+	"rock":     {0xB6, 0xA1, 0x36},
+	"ghost":    {0x73, 0x57, 0x97},
+	"dragon":   {0x6F, 0x35, 0xFC},
+	"dark":     {0x70, 0x57, 0x46},
+	"steel":    {0xB7, 0xB7, 0xCE},
+	"fairy":    {0xD6, 0x85, 0xAD},
+}
+
+// TypeColor returns the color of the Pokemon type called name, e.g. orange for "fire".
+func TypeColor(name string) (RGB, bool) {
+	c, ok := typeColors[name]
+	return c, ok
+}
+
+// StatColor returns the color for a base stat, from red for weak to cyan for exceptional.
+func StatColor(value int) RGB {
+	switch {
+	case value < 50:
+		return RGB{0xF3, 0x44, 0x44}
+	case value < 80:
+		return RGB{0xFF, 0x7F, 0x0F}
+	case value < 100:
+		return RGB{0xFF, 0xDD, 0x57}
+	case value < 120:
+		return RGB{0xA0, 0xE5, 0x15}
+	default:
+		return RGB{0x23, 0xCD, 0x5E}
+	}
+}
+
+// eighths are the block characters that fill 1/8 to 8/8 of a cell from the left.
+var eighths = []rune("▏▎▍▌▋▊▉█")
+
+// Bar draws value out of most as a bar width cells wide, in eighths of a cell.
+func Bar(value, most, width int) string {
+	if most <= 0 || width <= 0 {
+		return ""
+	}
+	filled := min(max(value, 0), most) * width * 8 / most
+	var b strings.Builder
+	b.WriteString(strings.Repeat(string(eighths[7]), filled/8))
+	if filled%8 > 0 {
+		b.WriteRune(eighths[filled%8-1])
+	}
+	return b.String()
+}
+
+// Width is the number of columns s takes up in a terminal, not counting its escape codes.
+// Emoji and East Asian characters take up two columns.
+func Width(s string) int {
+	width := 0
+	for _, r := range visibleRunes(s) {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// Truncate cuts s down to at most width columns, keeping its escape codes. Text cut off
+// mid-color is reset so the color doesn't run on past it.
+func Truncate(s string, width int) string {
+	var b strings.Builder
+	used, colored := 0, false
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			colored = s[i:i+n] != reset
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if used+runeWidth(r) > width {
+			break
+		}
+		b.WriteRune(r)
+		used += runeWidth(r)
+		i += size
+	}
+	if colored {
+		b.WriteString(reset)
+	}
+	return b.String()
+}
+
+// Pad truncates s to width columns and fills it out with spaces to exactly width columns.
+func Pad(s string, width int) string {
+	s = Truncate(s, width)
+	return s + strings.Repeat(" ", max(0, width-Width(s)))
+}
+
+// visibleRunes returns the runes of s outside its escape codes.
+func visibleRunes(s string) []rune {
+	var runes []rune
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		runes = append(runes, r)
+		i += size
+	}
+	return runes
+}
+
+// escapeLen is the length of the CSI escape code at the start of s, e.g. "\x1b[38;5;209m",
+// or 0 if s doesn't start with one.
+func escapeLen(s string) int {
+	if !strings.HasPrefix(s, "\x1b[") {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// runeWidth is the number of columns r takes up. Only the wide characters this program writes
+// are known: emoji like ✨ and the CJK blocks.
+func runeWidth(r rune) int {
+	switch {
+	case r == '\u2728' || r >= 0x1f300 && r <= 0x1faff:
+		return 2
+	case r >= 0x1100 && r <= 0x115f, r >= 0x2e80 && r <= 0xa4cf, r >= 0xac00 && r <= 0xd7a3, r >= 0xff00 && r <= 0xff60:
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,131 @@
+package ansi
+
+import (
+	"os"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEnabled(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cases := []struct {
+		name     string
+		mode     Mode
+		env      map[string]string
+		expected bool
+	}{
+		{name: "always, even into a file", mode: ModeAlways, env: map[string]string{"NO_COLOR": "1"}, expected: true},
+		{name: "never", mode: ModeNever, expected: false},
+		{name: "auto into a file", mode: ModeAuto, expected: false},
+		{name: "auto with NO_COLOR", mode: ModeAuto, env: map[string]string{"NO_COLOR": "1"}, expected: false},
+	}
+	for _, c := range cases {
+		if got := Enabled(c.mode, f, env(c.env)); got != c.expected {
+			t.Errorf("%s: got %v, expected %v", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestPalette(t *testing.T) {
+	fire, _ := TypeColor("fire")
+	cases := []struct {
+		name     string
+		palette  Palette
+		actual   string
+		expected string
+	}{
+		{name: "disabled", palette: Palette{}, actual: "fire", expected: "fire"},
+		{name: "truecolor", palette: Palette{Enabled: true, TrueColor: true}, expected: "\x1b[38;2;238;129;48mfire\x1b[0m"},
+		{name: "256 colors", palette: Palette{Enabled: true}, expected: "\x1b[38;5;209mfire\x1b[0m"},
+	}
+	for _, c := range cases {
+		if got := c.palette.Fg(fire, "fire"); got != c.expected {
+			t.Errorf("%s: got %q, expected %q", c.name, got, c.expected)
+		}
+	}
+	if got := (Palette{Enabled: true, TrueColor: true}).Bg(RGB{255, 255, 255}, "x"); got != "\x1b[48;2;255;255;255m\x1b[38;2;0;0;0mx\x1b[0m" {
+		t.Errorf("a light background should get dark text: %q", got)
+	}
+}
+
+func TestTo256(t *testing.T) {
+	cases := []struct {
+		color    RGB
+		expected uint8
+	}{
+		{color: RGB{0, 0, 0}, expected: 16},
+		{color: RGB{255, 255, 255}, expected: 231},
+		{color: RGB{255, 0, 0}, expected: 196},
+		{color: RGB{128, 128, 128}, expected: 244},
+		{color: RGB{0, 95, 135}, expected: 24},
+	}
+	for _, c := range cases {
+		if got := To256(c.color); got != c.expected {
+			t.Errorf("%v: got %d, expected %d", c.color, got, c.expected)
+		}
+	}
+}
+
+func TestBar(t *testing.T) {
+	cases := []struct {
+		value, most, width int
+		expected           string
+	}{
+		{value: 0, most: 100, width: 10, expected: ""},
+		{value: 100, most: 100, width: 10, expected: "██████████"},
+		{value: 55, most: 100, width: 10, expected: "█████▌"},
+		{value: 300, most: 255, width: 4, expected: "████"},
+		{value: 1, most: 255, width: 20, expected: ""},
+		{value: 13, most: 255, width: 20, expected: "█"},
+	}
+	for _, c := range cases {
+		got := Bar(c.value, c.most, c.width)
+		if got != c.expected {
+			t.Errorf("Bar(%d, %d, %d) = %q, expected %q", c.value, c.most, c.width, got, c.expected)
+		}
+		if n := utf8.RuneCountInString(got); n > c.width {
+			t.Errorf("Bar(%d, %d, %d) is %d cells wide", c.value, c.most, c.width, n)
+		}
+	}
+}
+
+func TestWidthAndTruncate(t *testing.T) {
+	p := Palette{Enabled: true}
+	fire, _ := TypeColor("fire")
+	colored := "a " + p.Fg(fire, "fire") + " type"
+
+	if got := Width(colored); got != 11 {
+		t.Errorf("Width(%q) = %d, expected 11", colored, got)
+	}
+	if got := Width("✨ mew"); got != 6 {
+		t.Errorf("Width of a shiny marker = %d, expected 6", got)
+	}
+
+	cases := []struct {
+		s        string
+		width    int
+		expected string
+	}{
+		{s: "pikachu", width: 4, expected: "pika"},
+		{s: "pikachu", width: 10, expected: "pikachu"},
+		{s: colored, width: 4, expected: "a \x1b[38;5;209mfi\x1b[0m"},
+		{s: colored, width: 20, expected: colored},
+		{s: "✨ mew", width: 1, expected: ""},
+	}
+	for _, c := range cases {
+		if got := Truncate(c.s, c.width); got != c.expected {
+			t.Errorf("Truncate(%q, %d) = %q, expected %q", c.s, c.width, got, c.expected)
+		}
+	}
+	if got := Pad("mew", 5); got != "mew  " {
+		t.Errorf("Pad(%q, 5) = %q", "mew", got)
+	}
+}
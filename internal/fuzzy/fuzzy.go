@@ -0,0 +1,82 @@
+// Package fuzzy finds the words someone most likely meant when they mistype one.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Distance returns the number of single character insertions, deletions, substitutions and
+// swaps of neighbouring characters it takes to turn a into b.
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// rows of the edit distance table: two back, one back and the one being filled in
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(t)]
+}
+
+// maxDistance is how far a candidate can be from word and still be suggested. Short words
+// allow fewer mistakes, so that "map" doesn't suggest every other three letter command.
+func maxDistance(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 4:
+		return 1
+	case n <= 8:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Suggest returns up to n of candidates that word is most likely a mistyping of, closest first.
+// Candidates equal to word are left out, as are ones too different from it to be a likely mistake.
+func Suggest(word string, candidates []string, n int) []string {
+	type match struct {
+		word     string
+		distance int
+	}
+	limit := maxDistance(word)
+	seen := make(map[string]bool)
+	var matches []match
+	for _, c := range candidates {
+		if c == word || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if d := Distance(strings.ToLower(word), strings.ToLower(c)); d <= limit {
+			matches = append(matches, match{c, d})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].word < matches[j].word
+	})
+	var suggestions []string
+	for i := 0; i < len(matches) && i < n; i++ {
+		suggestions = append(suggestions, matches[i].word)
+	}
+	return suggestions
+}
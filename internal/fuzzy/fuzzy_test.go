@@ -0,0 +1,56 @@
+package fuzzy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "", b: "", expected: 0},
+		{a: "map", b: "map", expected: 0},
+		{a: "", b: "map", expected: 3},
+		{a: "mpa", b: "map", expected: 1},
+		{a: "charmandr", b: "charmander", expected: 1},
+		{a: "pikachuu", b: "pikachu", expected: 1},
+		{a: "bulbasuar", b: "bulbasaur", expected: 1},
+		{a: "kitten", b: "sitting", expected: 3},
+		{a: "pokémon", b: "pokemon", expected: 1},
+	}
+	for _, c := range cases {
+		if d := Distance(c.a, c.b); d != c.expected {
+			t.Errorf("Distance(%q, %q) = %d, expected %d", c.a, c.b, d, c.expected)
+		}
+		if d := Distance(c.b, c.a); d != c.expected {
+			t.Errorf("Distance(%q, %q) = %d, expected %d", c.b, c.a, d, c.expected)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	commands := []string{"help", "exit", "map", "mapb", "explore", "catch", "inspect", "pokedex", "cache"}
+	cases := []struct {
+		word     string
+		expected []string
+	}{
+		{word: "mpa", expected: []string{"map"}},
+		{word: "mapp", expected: []string{"map", "mapb"}},
+		{word: "expolre", expected: []string{"explore"}},
+		{word: "cahce", expected: []string{"cache", "catch"}},
+		{word: "MAP", expected: []string{"map", "mapb"}},
+		{word: "map", expected: []string{"mapb"}},
+		{word: "fly", expected: nil},
+	}
+	for _, c := range cases {
+		if got := Suggest(c.word, commands, 3); !slices.Equal(got, c.expected) {
+			t.Errorf("Suggest(%q) = %q, expected %q", c.word, got, c.expected)
+		}
+	}
+
+	if got := Suggest("abc", []string{"abd", "abe", "abf", "xbc"}, 2); !slices.Equal(got, []string{"abd", "abe"}) {
+		t.Errorf("Suggest kept %q, expected the first 2 by distance then name", got)
+	}
+}
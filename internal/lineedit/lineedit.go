@@ -0,0 +1,400 @@
+// Package lineedit reads lines typed at a terminal with the editing keys people expect from a
+// shell: arrow keys and Emacs-style shortcuts to move around the line, history browsed with
+// up and down and searched with Ctrl-R, and Tab completion.
+//
+// When the input is not a terminal, or the platform has no raw terminal mode, lines are read
+// as they are with no editing.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/term"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl-C.
+// The line typed so far is thrown away.
+var ErrInterrupted = errors.New("interrupted")
+
+// DefaultMaxHistory is how many lines of history an Editor keeps unless told otherwise.
+const DefaultMaxHistory = 1000
+
+// Editor reads lines from a terminal.
+type Editor struct {
+	in  *os.File // the terminal, or nil when r isn't one
+	r   *bufio.Reader
+	out io.Writer
+
+	// Complete, when set, offers completions for Tab. It is given the words before the cursor,
+	// the last of which is the one being completed and may be empty, and returns every word that
+	// could go in its place. The Editor keeps the ones that start with what has been typed.
+	Complete func(words []string) []string
+
+	// MaxHistory is how many lines of history are kept.
+	MaxHistory int
+
+	history     []string
+	historyFile string
+}
+
+// New creates an Editor that reads from in and echoes to out.
+func New(in *os.File, out io.Writer) *Editor {
+	return &Editor{
+		in:         in,
+		r:          bufio.NewReader(in),
+		out:        out,
+		MaxHistory: DefaultMaxHistory,
+	}
+}
+
+// ReadLine shows prompt and returns the line the user types, without the newline.
+// It returns io.EOF when the user presses Ctrl-D on an empty line or the input ends, and
+// ErrInterrupted when they press Ctrl-C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.in == nil || !term.IsTerminal(int(e.in.Fd())) {
+		return e.readPlain(prompt)
+	}
+	restore, err := term.MakeRaw(int(e.in.Fd()))
+	if err != nil {
+		return e.readPlain(prompt)
+	}
+	defer restore()
+	return e.edit(prompt)
+}
+
+// readPlain reads a line without any editing.
+func (e *Editor) readPlain(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	line, err := e.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// lineState is the line being edited.
+type lineState struct {
+	prompt  string
+	buf     []rune
+	pos     int    // cursor position in buf
+	histIdx int    // the history entry shown, or len(history) for the line being typed
+	saved   []rune // the line being typed, kept while browsing history
+	lastTab bool   // the previous key was a Tab that could not complete any further
+}
+
+// edit reads a line from a terminal in raw mode.
+func (e *Editor) edit(prompt string) (string, error) {
+	s := &lineState{prompt: prompt, histIdx: len(e.history)}
+	e.refresh(s)
+
+	for {
+		key, err := term.ReadKey(e.r)
+		if err != nil {
+			return "", err
+		}
+		line, done, err := e.handleKey(s, key)
+		if done {
+			return line, err
+		}
+	}
+}
+
+// handleKey applies key to s. It reports done when the line is finished, along with the line
+// or the error ReadLine returns.
+func (e *Editor) handleKey(s *lineState, key rune) (line string, done bool, err error) {
+	tab := key == term.KeyTab
+	defer func() {
+		if !tab {
+			s.lastTab = false
+		}
+	}()
+
+	switch key {
+	case term.KeyEnter:
+		fmt.Fprint(e.out, "\n")
+		return string(s.buf), true, nil
+	case term.KeyCtrlC:
+		fmt.Fprint(e.out, "^C\n")
+		return "", true, ErrInterrupted
+	case term.KeyCtrlD:
+		if len(s.buf) == 0 {
+			fmt.Fprint(e.out, "\n")
+			return "", true, io.EOF
+		}
+		e.deleteAt(s, s.pos)
+	case term.KeyCtrlA, term.KeyHome:
+		s.pos = 0
+	case term.KeyCtrlE, term.KeyEnd:
+		s.pos = len(s.buf)
+	case term.KeyCtrlB, term.KeyLeft:
+		s.pos = max(s.pos-1, 0)
+	case term.KeyCtrlF, term.KeyRight:
+		s.pos = min(s.pos+1, len(s.buf))
+	case term.KeyCtrlH, term.KeyBackspace:
+		if s.pos > 0 {
+			s.pos--
+			e.deleteAt(s, s.pos)
+		}
+	case term.KeyDelete:
+		e.deleteAt(s, s.pos)
+	case term.KeyCtrlK:
+		s.buf = s.buf[:s.pos]
+	case term.KeyCtrlU:
+		s.buf = append([]rune{}, s.buf[s.pos:]...)
+		s.pos = 0
+	case term.KeyCtrlW:
+		start := s.pos
+		for start > 0 && unicode.IsSpace(s.buf[start-1]) {
+			start--
+		}
+		for start > 0 && !unicode.IsSpace(s.buf[start-1]) {
+			start--
+		}
+		s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+		s.pos = start
+	case term.KeyCtrlL:
+		fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+	case term.KeyCtrlP, term.KeyUp:
+		e.showHistory(s, s.histIdx-1)
+	case term.KeyCtrlN, term.KeyDown:
+		e.showHistory(s, s.histIdx+1)
+	case term.KeyTab:
+		e.complete(s)
+	case term.KeyCtrlR:
+		return e.search(s)
+	default:
+		if key < ' ' || key > unicode.MaxRune {
+			return "", false, nil
+		}
+		e.insert(s, []rune{key})
+	}
+	e.refresh(s)
+	return "", false, nil
+}
+
+// refresh redraws the prompt and line and puts the cursor back where it belongs.
+func (e *Editor) refresh(s *lineState) {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", s.prompt, string(s.buf))
+	if back := len(s.buf) - s.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+func (e *Editor) insert(s *lineState, text []rune) {
+	buf := make([]rune, 0, len(s.buf)+len(text))
+	buf = append(buf, s.buf[:s.pos]...)
+	buf = append(buf, text...)
+	s.buf = append(buf, s.buf[s.pos:]...)
+	s.pos += len(text)
+}
+
+func (e *Editor) deleteAt(s *lineState, i int) {
+	if i < len(s.buf) {
+		s.buf = append(s.buf[:i], s.buf[i+1:]...)
+	}
+}
+
+// showHistory replaces the line with history entry i, or with the line being typed when i is
+// just past the newest entry.
+func (e *Editor) showHistory(s *lineState, i int) {
+	if i < 0 || i > len(e.history) || i == s.histIdx {
+		return
+	}
+	if s.histIdx == len(e.history) {
+		s.saved = append([]rune{}, s.buf...)
+	}
+	s.histIdx = i
+	if i == len(e.history) {
+		s.buf = append([]rune{}, s.saved...)
+	} else {
+		s.buf = []rune(e.history[i])
+	}
+	s.pos = len(s.buf)
+}
+
+// complete finishes the word before the cursor. A single match is filled in with a space after
+// it. Several matches are filled in as far as they agree, and a second Tab lists them.
+func (e *Editor) complete(s *lineState) {
+	if e.Complete == nil {
+		return
+	}
+	before := s.buf[:s.pos]
+	words := strings.Fields(string(before))
+	if len(before) == 0 || unicode.IsSpace(before[len(before)-1]) {
+		words = append(words, "")
+	}
+	partial := words[len(words)-1]
+
+	seen := make(map[string]bool)
+	var matches []string
+	for _, c := range e.Complete(words) {
+		if strings.HasPrefix(c, partial) && !seen[c] {
+			seen[c] = true
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+
+	switch {
+	case len(matches) == 0:
+		fmt.Fprint(e.out, "\a")
+	case len(matches) == 1:
+		e.insert(s, []rune(strings.TrimPrefix(matches[0], partial)+" "))
+	default:
+		if prefix := commonPrefix(matches); len(prefix) > len(partial) {
+			e.insert(s, []rune(strings.TrimPrefix(prefix, partial)))
+			return
+		}
+		if s.lastTab {
+			fmt.Fprintf(e.out, "\n%s\n", strings.Join(matches, "  "))
+			return
+		}
+		s.lastTab = true
+		fmt.Fprint(e.out, "\a")
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// search runs a reverse incremental search through the history, started by Ctrl-R.
+// Typing narrows the search, Ctrl-R again finds an older match, Enter runs the match and
+// Ctrl-G cancels. Any other key keeps the match on the line for editing and is then handled
+// as usual.
+func (e *Editor) search(s *lineState) (string, bool, error) {
+	var query []rune
+	match := len(e.history) // index of the entry found, len(history) before anything is found
+	found := true
+
+	// find looks for the query in entries from the one at from back to the oldest
+	find := func(from int) {
+		for i := min(from, len(e.history)-1); i >= 0; i-- {
+			if strings.Contains(e.history[i], string(query)) {
+				match, found = i, true
+				return
+			}
+		}
+		found = false
+	}
+	draw := func() {
+		label := "reverse-i-search"
+		if !found {
+			label = "failed reverse-i-search"
+		}
+		shown := ""
+		if match < len(e.history) {
+			shown = e.history[match]
+		}
+		fmt.Fprintf(e.out, "\r(%s)`%s': %s\x1b[K", label, string(query), shown)
+	}
+	draw()
+
+	for {
+		key, err := term.ReadKey(e.r)
+		if err != nil {
+			return "", true, err
+		}
+		switch {
+		case key == term.KeyCtrlR:
+			if len(query) > 0 {
+				find(match - 1)
+			}
+		case key == term.KeyCtrlH || key == term.KeyBackspace:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(e.history) - 1)
+			}
+		case key == term.KeyCtrlG || key == term.KeyCtrlC:
+			e.refresh(s)
+			return "", false, nil
+		case key >= ' ' && key <= unicode.MaxRune:
+			query = append(query, key)
+			find(match)
+		default:
+			if match < len(e.history) {
+				s.buf = []rune(e.history[match])
+				s.pos = len(s.buf)
+				s.histIdx = len(e.history)
+			}
+			e.refresh(s)
+			return e.handleKey(s, key)
+		}
+		draw()
+	}
+}
+
+// History returns the remembered lines, oldest first.
+func (e *Editor) History() []string {
+	return append([]string{}, e.history...)
+}
+
+// AddHistory remembers line so it can be recalled later, unless it is blank or the same as the
+// line before it. With a history file set by LoadHistory, the line is also added to the file.
+func (e *Editor) AddHistory(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return nil
+	}
+	e.history = append(e.history, line)
+	if over := len(e.history) - e.MaxHistory; over > 0 {
+		e.history = e.history[over:]
+	}
+	if e.historyFile == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error: could not save history: %w", err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, line)
+	return err
+}
+
+// LoadHistory reads the history saved in path, which does not have to exist yet, and keeps
+// adding to it from then on. A file that has grown past MaxHistory lines is cut back down.
+func (e *Editor) LoadHistory(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error: could not create history directory: %w", err)
+	}
+	e.historyFile = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error: could not read history: %w", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	var loaded []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			loaded = append(loaded, line)
+		}
+	}
+	if over := len(loaded) - e.MaxHistory; over > 0 {
+		loaded = loaded[over:]
+		if err := os.WriteFile(path, []byte(strings.Join(loaded, "\n")+"\n"), 0o600); err != nil {
+			return fmt.Errorf("error: could not trim history: %w", err)
+		}
+	}
+	e.history = append(loaded, e.history...)
+	return nil
+}
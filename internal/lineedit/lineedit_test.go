@@ -0,0 +1,172 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestEditor returns an Editor that reads the key presses in keys, as if typed at a terminal.
+func newTestEditor(keys string, history ...string) *Editor {
+	return &Editor{
+		r:          bufio.NewReader(strings.NewReader(keys)),
+		out:        io.Discard,
+		MaxHistory: DefaultMaxHistory,
+		history:    history,
+	}
+}
+
+func TestEdit(t *testing.T) {
+	cases := []struct {
+		name     string
+		keys     string
+		history  []string
+		expected string
+	}{
+		{name: "typing", keys: "map\r", expected: "map"},
+		{name: "backspace", keys: "mapx\x7f\r", expected: "map"},
+		{name: "insert after moving left", keys: "mp\x1b[Da\r", expected: "map"},
+		{name: "home and end", keys: "ap\x01m\x05b\r", expected: "mapb"},
+		{name: "delete key", keys: "mxap\x01\x1b[C\x1b[3~\r", expected: "map"},
+		{name: "ctrl-w deletes a word", keys: "explore canalave \x17pastoria\r", expected: "explore pastoria"},
+		{name: "ctrl-u deletes to the start", keys: "catch pikachu\x15map\r", expected: "map"},
+		{name: "ctrl-k deletes to the end", keys: "mapxyz\x1b[D\x1b[D\x1b[D\x0b\r", expected: "map"},
+		{name: "up recalls history", keys: "\x1b[A\x1b[A\r", history: []string{"map", "explore x"}, expected: "map"},
+		{name: "down returns to the typed line", keys: "cat\x1b[A\x1b[B\r", history: []string{"map"}, expected: "cat"},
+		{name: "up stops at the oldest", keys: "\x10\x10\x10\r", history: []string{"map"}, expected: "map"},
+		{name: "utf-8", keys: "pokémon\x7f\x7fn\r", expected: "pokémn"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			line, err := newTestEditor(c.keys, c.history...).edit("> ")
+			if err != nil || line != c.expected {
+				t.Errorf("got %q, %v; expected %q", line, err, c.expected)
+			}
+		})
+	}
+}
+
+func TestEditEndings(t *testing.T) {
+	if _, err := newTestEditor("\x04").edit("> "); err != io.EOF {
+		t.Errorf("ctrl-d on an empty line: got %v, expected io.EOF", err)
+	}
+	if line, err := newTestEditor("mapp\x01\x1b[C\x1b[C\x1b[C\x04\r").edit("> "); err != nil || line != "map" {
+		t.Errorf("ctrl-d on a line deletes: got %q, %v", line, err)
+	}
+	if _, err := newTestEditor("map\x03").edit("> "); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("ctrl-c: got %v, expected ErrInterrupted", err)
+	}
+	if _, err := newTestEditor("map").edit("> "); err != io.EOF {
+		t.Errorf("input ending: got %v, expected io.EOF", err)
+	}
+}
+
+func TestLoneEscape(t *testing.T) {
+	// Escape pressed on its own arrives without the rest of a sequence, so the next key is typed
+	e := newTestEditor("")
+	e.r = bufio.NewReader(io.MultiReader(strings.NewReader("ma"), strings.NewReader("\x1b"), strings.NewReader("p\r")))
+	if line, err := e.edit("> "); err != nil || line != "map" {
+		t.Errorf("got %q, %v; expected \"map\"", line, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	history := []string{"explore canalave-city-area", "catch tentacool", "explore eterna-city-area", "map"}
+	cases := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{name: "newest match", keys: "\x12expl\r", expected: "explore eterna-city-area"},
+		{name: "ctrl-r again finds an older match", keys: "\x12expl\x12\r", expected: "explore canalave-city-area"},
+		{name: "backspace widens the search", keys: "\x12catx\x7f\r", expected: "catch tentacool"},
+		{name: "other keys keep the match to edit", keys: "\x12tenta\x05 now\r", expected: "catch tentacool now"},
+		{name: "ctrl-g cancels", keys: "ma\x12expl\x07p\r", expected: "map"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			line, err := newTestEditor(c.keys, history...).edit("> ")
+			if err != nil || line != c.expected {
+				t.Errorf("got %q, %v; expected %q", line, err, c.expected)
+			}
+		})
+	}
+}
+
+func TestComplete(t *testing.T) {
+	complete := func(words []string) []string {
+		if len(words) == 1 {
+			return []string{"map", "mapb", "explore", "exit"}
+		}
+		if words[0] == "explore" {
+			return []string{"canalave-city-area", "eterna-city-area", "eterna-forest-area"}
+		}
+		return nil
+	}
+	cases := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{name: "single match gets a space", keys: "exp\t\r", expected: "explore "},
+		{name: "common prefix", keys: "ma\t\r", expected: "map"},
+		{name: "arguments", keys: "explore ca\t\r", expected: "explore canalave-city-area "},
+		{name: "prefix of arguments", keys: "explore et\tc\t\r", expected: "explore eterna-city-area "},
+		{name: "no match", keys: "catch pi\t\r", expected: "catch pi"},
+		{name: "completes in the middle of the line", keys: "exi canalave\x01\x06\x06\x06\t\r", expected: "exit  canalave"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := newTestEditor(c.keys)
+			e.Complete = complete
+			line, err := e.edit("> ")
+			if err != nil || line != c.expected {
+				t.Errorf("got %q, %v; expected %q", line, err, c.expected)
+			}
+		})
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pokedex", "history")
+
+	e := newTestEditor("")
+	if err := e.LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"map", "map", "  ", "explore canalave-city-area"} {
+		if err := e.AddHistory(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reloaded := newTestEditor("")
+	reloaded.MaxHistory = 1
+	if err := reloaded.LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.History(); !slices.Equal(got, []string{"explore canalave-city-area"}) {
+		t.Errorf("history %q", got)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "explore canalave-city-area\n" {
+		t.Errorf("history file was not trimmed: %q", data)
+	}
+}
+
+func TestReadLineWithoutTerminal(t *testing.T) {
+	e := newTestEditor("map\nexplore x")
+	for _, expected := range []string{"map", "explore x"} {
+		if line, err := e.ReadLine("> "); err != nil || line != expected {
+			t.Errorf("got %q, %v; expected %q", line, err, expected)
+		}
+	}
+	if _, err := e.ReadLine("> "); err != io.EOF {
+		t.Errorf("got %v, expected io.EOF", err)
+	}
+}
@@ -0,0 +1,237 @@
+// Package mockapi serves recorded PokeAPI responses so the Pokedex can be developed and tested
+// without the network. It answers the same URLs as https://pokeapi.co/api/v2, with list pages
+// whose next and previous links point back at the mock server.
+package mockapi
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures
+var embedded embed.FS
+
+// APIPrefix is the path the mock API is served under, matching PokeAPI's.
+const APIPrefix = "/api/v2/"
+
+// defaultLimit is how many results a list page has when the request doesn't say.
+const defaultLimit = 20
+
+// Options configure a mock server.
+type Options struct {
+	// Fixtures holds one directory per resource, e.g. pokemon, each with a <name>.json file per
+	// resource. The fixtures embedded in this package are used when it is nil.
+	Fixtures fs.FS
+	// Latency is added before every response.
+	Latency time.Duration
+	// ErrorRate is the fraction of requests, from 0 to 1, answered with 500 Internal Server Error.
+	ErrorRate float64
+	// Seed seeds the random choice of which requests fail, so runs can be repeated.
+	Seed int64
+}
+
+// resource is one fixture file.
+type resource struct {
+	id   int
+	name string
+	body []byte
+}
+
+// handler is the http.Handler returned by NewHandler.
+type handler struct {
+	opts      Options
+	resources map[string][]resource // resource type -> resources sorted by id, then name
+
+	mu  sync.Mutex // protects rng
+	rng *rand.Rand
+}
+
+// NewHandler loads the fixtures and returns a handler serving them under APIPrefix.
+func NewHandler(opts Options) (http.Handler, error) {
+	if opts.ErrorRate < 0 || opts.ErrorRate > 1 {
+		return nil, errors.New("error rate must be between 0 and 1")
+	}
+	fixtures := opts.Fixtures
+	if fixtures == nil {
+		sub, err := fs.Sub(embedded, "fixtures")
+		if err != nil {
+			return nil, err
+		}
+		fixtures = sub
+	}
+
+	resources, err := loadFixtures(fixtures)
+	if err != nil {
+		return nil, err
+	}
+	return &handler{
+		opts:      opts,
+		resources: resources,
+		rng:       rand.New(rand.NewSource(opts.Seed)),
+	}, nil
+}
+
+// loadFixtures reads every <type>/<name>.json file in fixtures.
+func loadFixtures(fixtures fs.FS) (map[string][]resource, error) {
+	resources := make(map[string][]resource)
+	files, err := fs.Glob(fixtures, "*/*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		body, err := fs.ReadFile(fixtures, file)
+		if err != nil {
+			return nil, err
+		}
+		var doc struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", file, err)
+		}
+		if doc.Name == "" {
+			doc.Name = strings.TrimSuffix(path.Base(file), ".json")
+		}
+		kind := path.Dir(file)
+		resources[kind] = append(resources[kind], resource{id: doc.ID, name: doc.Name, body: body})
+	}
+	for _, list := range resources {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].id != list[j].id {
+				return list[i].id < list[j].id
+			}
+			return list[i].name < list[j].name
+		})
+	}
+	return resources, nil
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Latency > 0 {
+		select {
+		case <-time.After(h.opts.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if h.shouldFail() {
+		http.Error(w, "injected error", http.StatusInternalServerError)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, APIPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	kind, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, APIPrefix), "/")
+	name = strings.TrimSuffix(name, "/")
+	list, ok := h.resources[kind]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if name == "" {
+		h.serveList(w, r, kind, list)
+		return
+	}
+	for _, res := range list {
+		if res.name == name || strconv.Itoa(res.id) == name {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(res.body)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// shouldFail decides whether to inject an error into this response.
+func (h *handler) shouldFail() bool {
+	if h.opts.ErrorRate == 0 {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rng.Float64() < h.opts.ErrorRate
+}
+
+// listPage is PokeAPI's paginated list of resources.
+type listPage struct {
+	Count    int       `json:"count"`
+	Next     *string   `json:"next"`
+	Previous *string   `json:"previous"`
+	Results  []listRef `json:"results"`
+}
+
+type listRef struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// serveList writes one page of the resources of a kind, honouring the limit and offset query parameters.
+func (h *handler) serveList(w http.ResponseWriter, r *http.Request, kind string, list []resource) {
+	limit, err := queryInt(r, "limit", defaultLimit)
+	if err != nil || limit < 1 {
+		http.Error(w, "bad limit", http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "bad offset", http.StatusBadRequest)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host + APIPrefix + kind + "/"
+	pageURL := func(offset int) *string {
+		u := fmt.Sprintf("%s?offset=%d&limit=%d", base, offset, limit)
+		return &u
+	}
+
+	page := listPage{Count: len(list), Results: []listRef{}}
+	for i := offset; i < len(list) && i < offset+limit; i++ {
+		ref := list[i].name
+		if list[i].id != 0 {
+			ref = strconv.Itoa(list[i].id)
+		}
+		page.Results = append(page.Results, listRef{Name: list[i].name, URL: base + ref + "/"})
+	}
+	if offset+limit < len(list) {
+		page.Next = pageURL(offset + limit)
+	}
+	if offset > 0 {
+		page.Previous = pageURL(max(offset-limit, 0))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(page)
+}
+
+// queryInt reads an integer query parameter, returning def if it isn't set.
+func queryInt(r *http.Request, key string, def int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
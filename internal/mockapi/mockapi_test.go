@@ -0,0 +1,114 @@
+package mockapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	handler, err := NewHandler(opts)
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decoding %s: %v", url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestPagination(t *testing.T) {
+	server := newTestServer(t, Options{})
+	base := server.URL + APIPrefix + "location-area/"
+
+	var page listPage
+	if code := getJSON(t, base+"?limit=3&offset=0", &page); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if page.Count != 7 || len(page.Results) != 3 {
+		t.Fatalf("expected count 7 with 3 results, got count %d with %d results", page.Count, len(page.Results))
+	}
+	if page.Results[0].Name != "canalave-city-area" {
+		t.Errorf("expected canalave-city-area first, got %q", page.Results[0].Name)
+	}
+	if page.Previous != nil {
+		t.Errorf("expected no previous page before the first, got %q", *page.Previous)
+	}
+	if page.Next == nil || *page.Next != base+"?offset=3&limit=3" {
+		t.Errorf("expected next page %s, got %v", base+"?offset=3&limit=3", page.Next)
+	}
+
+	var last listPage
+	getJSON(t, base+"?limit=3&offset=6", &last)
+	if last.Next != nil {
+		t.Errorf("expected no next page after the last, got %q", *last.Next)
+	}
+	if last.Previous == nil || *last.Previous != base+"?offset=3&limit=3" {
+		t.Errorf("expected previous page %s, got %v", base+"?offset=3&limit=3", last.Previous)
+	}
+	if len(last.Results) != 1 {
+		t.Errorf("expected 1 result on the last page, got %d", len(last.Results))
+	}
+}
+
+func TestDetail(t *testing.T) {
+	server := newTestServer(t, Options{})
+
+	var byName, byID struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if code := getJSON(t, server.URL+APIPrefix+"pokemon/pikachu", &byName); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	getJSON(t, server.URL+APIPrefix+"pokemon/25/", &byID)
+	if byName.ID != 25 || byID.Name != "pikachu" {
+		t.Errorf("expected pikachu with id 25 both ways, got %+v and %+v", byName, byID)
+	}
+
+	for _, path := range []string{"pokemon/mewtwo", "berry/", "nope"} {
+		if code := getJSON(t, server.URL+APIPrefix+path, nil); code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", path, code)
+		}
+	}
+}
+
+func TestErrorInjection(t *testing.T) {
+	server := newTestServer(t, Options{ErrorRate: 1})
+	if code := getJSON(t, server.URL+APIPrefix+"pokemon/pikachu", nil); code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", code)
+	}
+
+	if _, err := NewHandler(Options{ErrorRate: 2}); err == nil {
+		t.Error("expected NewHandler to refuse an error rate above 1")
+	}
+}
+
+func TestCustomFixtures(t *testing.T) {
+	server := newTestServer(t, Options{Fixtures: fstest.MapFS{
+		"berry/cheri.json": {Data: []byte(`{"id": 1, "name": "cheri"}`)},
+	}})
+
+	var page listPage
+	getJSON(t, server.URL+APIPrefix+"berry/", &page)
+	if page.Count != 1 || len(page.Results) != 1 || page.Results[0].URL != server.URL+APIPrefix+"berry/1/" {
+		t.Errorf("expected cheri at %s, got %+v", server.URL+APIPrefix+"berry/1/", page)
+	}
+}
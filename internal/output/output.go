@@ -0,0 +1,140 @@
+// Package output renders the results of Pokedex commands for people or for other programs.
+// A result is any value that can be marshalled to JSON. Results that implement Texter control how
+// they look as plain text, results that implement ColorTexter also control how they look in a
+// colored terminal, and results that implement Tabular can also be shown as a table or CSV.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+)
+
+// Format is a way of rendering results.
+type Format string
+
+const (
+	FormatText  Format = "text"  // human readable text, the default
+	FormatTable Format = "table" // columns aligned with spaces
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+	FormatCSV   Format = "csv"
+)
+
+// Formats lists every Format, in the order they are shown in help.
+var Formats = []Format{FormatText, FormatTable, FormatJSON, FormatYAML, FormatCSV}
+
+// ParseFormat returns the Format called name.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown output format %q, use one of %s", name, strings.Join(names, ", "))
+}
+
+// Texter is implemented by results with their own plain text form.
+type Texter interface {
+	// Text returns the result as people read it at the prompt, ending in a newline.
+	Text() string
+}
+
+// ColorTexter is implemented by results that look better with colors in a terminal. ColorText
+// is only used when the palette is enabled, and Text otherwise, so piped output stays plain.
+type ColorTexter interface {
+	Texter
+	ColorText(p ansi.Palette) string
+}
+
+// Tabular is implemented by results that can be laid out as rows and columns.
+type Tabular interface {
+	Columns() []string
+	Rows() [][]string
+}
+
+// Write renders v to w in format f.
+func Write(w io.Writer, f Format, v any) error {
+	return WriteColor(w, f, v, ansi.Palette{})
+}
+
+// WriteColor renders v to w in format f, coloring the text format with p.
+// The other formats are meant for programs and are never colored.
+func WriteColor(w io.Writer, f Format, v any, p ansi.Palette) error {
+	switch f {
+	case FormatText, "":
+		if t, ok := v.(ColorTexter); ok && p.Enabled {
+			_, err := io.WriteString(w, t.ColorText(p))
+			return err
+		}
+		if t, ok := v.(Texter); ok {
+			_, err := io.WriteString(w, t.Text())
+			return err
+		}
+		if t, ok := v.(Tabular); ok {
+			return writeTable(w, t)
+		}
+		_, err := fmt.Fprintln(w, v)
+		return err
+	case FormatTable, FormatCSV:
+		t, ok := v.(Tabular)
+		if !ok {
+			return fmt.Errorf("this result can't be shown as %s, try json or yaml", f)
+		}
+		if f == FormatCSV {
+			return writeCSV(w, t)
+		}
+		return writeTable(w, t)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(v)
+	case FormatYAML:
+		return writeYAML(w, v)
+	default:
+		return fmt.Errorf("unknown output format %q", f)
+	}
+}
+
+func writeTable(w io.Writer, t Tabular) error {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	header := make([]string, len(t.Columns()))
+	for i, c := range t.Columns() {
+		header[i] = strings.ToUpper(c)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range t.Rows() {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// rows ending in empty cells are padded out to the full width, which isn't wanted
+	lines := strings.SplitAfter(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \n")
+		if strings.HasSuffix(line, "\n") {
+			lines[i] += "\n"
+		}
+	}
+	_, err := io.WriteString(w, strings.Join(lines, ""))
+	return err
+}
+
+func writeCSV(w io.Writer, t Tabular) error {
+	cw := csv.NewWriter(w)
+	cw.Write(t.Columns())
+	cw.WriteAll(t.Rows()) // flushes
+	return cw.Error()
+}
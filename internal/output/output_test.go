@@ -0,0 +1,159 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+)
+
+type area struct {
+	Name    string   `json:"name"`
+	Pokemon []string `json:"pokemon"`
+}
+
+type areaList struct {
+	Areas []area `json:"areas"`
+	Next  string `json:"next"`
+}
+
+func (l areaList) Columns() []string { return []string{"name", "pokemon"} }
+
+func (l areaList) Rows() [][]string {
+	rows := make([][]string, len(l.Areas))
+	for i, a := range l.Areas {
+		rows[i] = []string{a.Name, strings.Join(a.Pokemon, " ")}
+	}
+	return rows
+}
+
+type greeting string
+
+func (g greeting) Text() string { return "hello " + string(g) + "\n" }
+
+func (g greeting) ColorText(p ansi.Palette) string { return "hello " + p.Bold(string(g)) + "\n" }
+
+func TestWrite(t *testing.T) {
+	areas := areaList{
+		Areas: []area{
+			{Name: "canalave-city-area", Pokemon: []string{"tentacool", "wingull"}},
+			{Name: "oreburgh-mine-1f", Pokemon: []string{}},
+		},
+		Next: "",
+	}
+
+	cases := []struct {
+		name     string
+		format   Format
+		value    any
+		expected string
+	}{
+		{
+			name:     "text uses Text",
+			format:   FormatText,
+			value:    greeting("pikachu"),
+			expected: "hello pikachu\n",
+		},
+		{
+			name:     "text falls back to a table",
+			format:   FormatText,
+			value:    areas,
+			expected: "NAME                POKEMON\ncanalave-city-area  tentacool wingull\noreburgh-mine-1f\n",
+		},
+		{
+			name:     "table",
+			format:   FormatTable,
+			value:    areas,
+			expected: "NAME                POKEMON\ncanalave-city-area  tentacool wingull\noreburgh-mine-1f\n",
+		},
+		{
+			name:     "csv",
+			format:   FormatCSV,
+			value:    areas,
+			expected: "name,pokemon\ncanalave-city-area,tentacool wingull\noreburgh-mine-1f,\n",
+		},
+		{
+			name:     "json",
+			format:   FormatJSON,
+			value:    area{Name: "a&b", Pokemon: nil},
+			expected: "{\n  \"name\": \"a&b\",\n  \"pokemon\": null\n}\n",
+		},
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			value:  areas,
+			expected: `areas:
+  - name: canalave-city-area
+    pokemon:
+      - tentacool
+      - wingull
+  - name: oreburgh-mine-1f
+    pokemon: []
+next: ""
+`,
+		},
+		{
+			name:     "yaml scalars that need quotes",
+			format:   FormatYAML,
+			value:    map[string]any{"a": "true", "b": "12", "c": "x: y", "d": 12, "e": nil},
+			expected: "a: \"true\"\nb: \"12\"\nc: \"x: y\"\nd: 12\ne: null\n",
+		},
+		{
+			name:     "yaml of a scalar",
+			format:   FormatYAML,
+			value:    "pikachu",
+			expected: "pikachu\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Write(&b, c.format, c.value); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != c.expected {
+				t.Errorf("got:\n%s\nexpected:\n%s", b.String(), c.expected)
+			}
+		})
+	}
+}
+
+func TestWriteColor(t *testing.T) {
+	colors := ansi.Palette{Enabled: true}
+	cases := []struct {
+		name     string
+		format   Format
+		palette  ansi.Palette
+		expected string
+	}{
+		{name: "colored text", format: FormatText, palette: colors, expected: "hello \x1b[1mpikachu\x1b[0m\n"},
+		{name: "disabled palette", format: FormatText, expected: "hello pikachu\n"},
+		{name: "json is never colored", format: FormatJSON, palette: colors, expected: "\"pikachu\"\n"},
+	}
+	for _, c := range cases {
+		var b strings.Builder
+		if err := WriteColor(&b, c.format, greeting("pikachu"), c.palette); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != c.expected {
+			t.Errorf("%s: got %q, expected %q", c.name, b.String(), c.expected)
+		}
+	}
+}
+
+func TestWriteTableNeedsTabular(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, FormatTable, greeting("pikachu")); err == nil {
+		t.Error("expected an error rendering a non-tabular result as a table")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("yaml"); err != nil || f != FormatYAML {
+		t.Errorf("ParseFormat(yaml) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml) should fail")
+	}
+}
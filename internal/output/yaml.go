@@ -0,0 +1,146 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// node is a decoded JSON value that remembers the order of object keys, so YAML output lists
+// fields in the same order as JSON output does.
+type node struct {
+	scalar any // string, json.Number, bool or nil, for anything that isn't an object or array
+	keys   []string
+	values []*node
+	isMap  bool
+	isList bool
+}
+
+// writeYAML renders v as YAML by way of its JSON encoding, so both formats agree on field names.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := decodeNode(dec)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	switch {
+	case n.isMap && len(n.keys) > 0, n.isList && len(n.values) > 0:
+		writeYAMLNode(&b, n, 0)
+	default:
+		b.WriteString(yamlScalar(n) + "\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func decodeNode(dec *json.Decoder) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		n := &node{isMap: true}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, key.(string))
+			n.values = append(n.values, value)
+		}
+		_, err := dec.Token() // }
+		return n, err
+	case json.Delim('['):
+		n := &node{isList: true}
+		for dec.More() {
+			value, err := decodeNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, value)
+		}
+		_, err := dec.Token() // ]
+		return n, err
+	default:
+		return &node{scalar: tok}, nil
+	}
+}
+
+// writeYAMLNode writes a non-empty object or array, one entry per line, indented by indent spaces.
+func writeYAMLNode(b *strings.Builder, n *node, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for i, value := range n.values {
+		prefix := "- "
+		if n.isMap {
+			prefix = yamlString(n.keys[i]) + ":"
+		}
+		switch {
+		case (value.isMap && len(value.keys) > 0) || (value.isList && len(value.values) > 0):
+			if n.isList && value.isMap {
+				// the first field of an object in a list goes on the same line as its dash
+				var item strings.Builder
+				writeYAMLNode(&item, value, indent+2)
+				b.WriteString(pad + "- " + strings.TrimPrefix(item.String(), pad+"  "))
+				continue
+			}
+			b.WriteString(pad + strings.TrimSuffix(prefix, " ") + "\n")
+			writeYAMLNode(b, value, indent+2)
+		case n.isMap:
+			b.WriteString(pad + prefix + " " + yamlScalar(value) + "\n")
+		default:
+			b.WriteString(pad + prefix + yamlScalar(value) + "\n")
+		}
+	}
+}
+
+// yamlScalar renders a scalar, or an empty object or array, on one line.
+func yamlScalar(n *node) string {
+	switch {
+	case n.isMap:
+		return "{}"
+	case n.isList:
+		return "[]"
+	}
+	switch v := n.scalar.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// yamlString quotes s when it would otherwise be read back as something other than the same string.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "", "null", "~", "true", "false", "yes", "no", "on", "off":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	if strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t\\") || strings.TrimSpace(s) != s || strings.HasPrefix(s, "-") || strings.HasPrefix(s, "?") {
+		return strconv.Quote(s)
+	}
+	return s
+}
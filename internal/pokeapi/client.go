@@ -0,0 +1,220 @@
+package pokeapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokestore"
+)
+
+// DefaultBaseURL is the root of the PokeAPI endpoints that a Client calls.
+const DefaultBaseURL = "https://pokeapi.co/api/v2"
+
+// ErrNotFound is returned when the API has nothing at a URL, e.g. for a misspelled Pokemon.
+var ErrNotFound = errors.New("not found")
+
+// Client fetches data from PokeAPI.
+// Raw responses are kept in a byte cache shared with the rest of the program, and decoded
+// Pokemon are kept in a cache of their own so repeated lookups skip json.Unmarshal.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	cache   *pokecache.ByteCache
+	pokemon *pokecache.Cache[string, decodedPokemon] // decoded Pokemon keyed by URL
+
+	// snapshot serves every response instead of the network when the Client is offline.
+	snapshot *pokestore.Store
+	// saveTo, when set, gets a copy of every response the Client uses. Sync sets it.
+	saveTo *pokestore.Store
+	// logger receives notes about where data came from, kept apart from the data itself.
+	logger *log.Logger
+
+	// network counts calls to the network, for showing network activity. It is shared with
+	// copies of the Client, such as the one Sync uses.
+	network *networkCounters
+}
+
+type networkCounters struct {
+	requests atomic.Int64
+	inFlight atomic.Int64
+}
+
+// NetworkStats describes the Client's use of the network.
+type NetworkStats struct {
+	Requests int64 // requests sent so far, including ones answered with 304 Not Modified
+	InFlight int64 // requests waiting for a response right now
+}
+
+// ClientOption configures optional Client behaviour in NewClient.
+type ClientOption func(*Client)
+
+// WithBaseURL points the Client at another PokeAPI-compatible server, such as the mock one in
+// internal/mockapi. baseURL is the root of the endpoints, like DefaultBaseURL.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithTransport sends the Client's requests through rt instead of http.DefaultTransport,
+// for example a Recorder.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.http = &http.Client{Transport: rt}
+	}
+}
+
+// WithLogger sends the Client's diagnostic messages, such as warnings about stale data, to logger.
+// By default they go to stderr.
+func WithLogger(logger *log.Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithOffline makes the Client serve everything from the snapshot in store instead of calling
+// the API. Anything that was not synced into the snapshot fails with pokestore.ErrNotSynced.
+func WithOffline(store *pokestore.Store) ClientOption {
+	return func(c *Client) {
+		c.snapshot = store
+	}
+}
+
+// decodedPokemon is a Pokemon decoded from the cached response with the given generation, see
+// pokecache.Cache.Generation. It is out of date once the response has a different generation.
+type decodedPokemon struct {
+	Pokemon
+	generation uint64
+}
+
+// NewClient creates a Client that caches responses in cache.
+// Decoded values stay cached for as long as cache keeps raw responses fresh.
+func NewClient(cache *pokecache.ByteCache, opts ...ClientOption) (*Client, error) {
+	pokemon, err := pokecache.NewCache[string, decodedPokemon](cache.Interval())
+	if err != nil {
+		return nil, fmt.Errorf("error: could not create Pokemon cache: %w", err)
+	}
+	c := &Client{
+		baseURL: DefaultBaseURL,
+		http:    http.DefaultClient,
+		logger:  log.New(os.Stderr, "", 0),
+		cache:   cache,
+		pokemon: pokemon,
+		network: &networkCounters{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Network returns how much the Client has used the network.
+func (c *Client) Network() NetworkStats {
+	return NetworkStats{Requests: c.network.requests.Load(), InFlight: c.network.inFlight.Load()}
+}
+
+// Evict removes the response for url from the cache, along with anything decoded from it.
+// Returns false if the response wasn't cached.
+func (c *Client) Evict(url string) bool {
+	c.pokemon.Evict(url)
+	return c.cache.Evict(url)
+}
+
+// Clear removes every cached response, along with anything decoded from them, and returns how
+// many responses were removed.
+func (c *Client) Clear() int {
+	c.pokemon.Clear()
+	return c.cache.Clear()
+}
+
+// Offline reports whether the Client serves everything from a snapshot.
+func (c *Client) Offline() bool {
+	return c.snapshot != nil
+}
+
+// LocationAreasURL returns the URL of the first page of location areas, with limit areas per page.
+func (c *Client) LocationAreasURL(limit int) string {
+	return fmt.Sprintf("%s/location-area/?limit=%d&offset=0", c.baseURL, limit)
+}
+
+// Close stops the Client's own caches. The byte cache passed to NewClient is left running.
+func (c *Client) Close() {
+	c.pokemon.Stop()
+}
+
+// getCached returns the response body for url, calling the API only when it isn't already cached.
+// Concurrent misses for the same url share one request.
+// Expired data the cache is still holding on to is marked as stale when it is used.
+func (c *Client) getCached(url string) ([]byte, error) {
+	body, stale, err := c.cache.GetOrFetch(url, func(prev pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		if c.snapshot != nil {
+			body, err := c.snapshot.Load(url)
+			if errors.Is(err, pokestore.ErrNotSynced) {
+				return pokecache.FetchResult[[]byte]{}, fmt.Errorf("offline: %w, run \"sync\" while online to download it", err)
+			}
+			return pokecache.FetchResult[[]byte]{Val: body}, err
+		}
+		return c.fetch(url, prev)
+	})
+	if stale {
+		c.logger.Println("(stale) showing cached data, it may be out of date")
+	}
+	if err == nil && c.saveTo != nil {
+		if err := c.saveTo.Save(url, body); err != nil {
+			return nil, fmt.Errorf("error: could not save %s to the snapshot: %w", url, err)
+		}
+	}
+	return body, err
+}
+
+// fetch calls the API for url.
+// prev holds the validators saved from the last response for url, which are sent along so the
+// API can answer 304 Not Modified instead of sending the whole body again.
+func (c *Client) fetch(url string, prev pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return pokecache.FetchResult[[]byte]{}, err
+	}
+	if prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+
+	c.network.requests.Add(1)
+	c.network.inFlight.Add(1)
+	defer c.network.inFlight.Add(-1)
+	res, err := c.http.Do(req)
+	if err != nil {
+		return pokecache.FetchResult[[]byte]{}, err
+	}
+	defer res.Body.Close()
+
+	validators := pokecache.Validators{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+	if res.StatusCode == http.StatusNotModified {
+		return pokecache.FetchResult[[]byte]{Validators: validators, NotModified: true}, nil
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return pokecache.FetchResult[[]byte]{}, fmt.Errorf("%w: %s", ErrNotFound, url)
+	}
+	if res.StatusCode != http.StatusOK {
+		return pokecache.FetchResult[[]byte]{}, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return pokecache.FetchResult[[]byte]{}, fmt.Errorf("error: could not read response body: %w", err)
+	}
+	return pokecache.FetchResult[[]byte]{Val: body, Validators: validators}, nil
+}
@@ -4,10 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-
-	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
 )
 
 // struct to capture json response from GetLocationAreas
@@ -23,34 +19,20 @@ type LocationAreasResponse struct {
 
 // GetLocationAreas pulls down number of locations areas from the API using the given "url".
 // Returns a slice of location area names, the Next url for the next page of results, and an error.
-func GetLocationAreas(url string, locationCache *pokecache.Cache) ([]string, string, string, error) {
+func (c *Client) GetLocationAreas(url string) ([]string, string, string, error) {
 	// if passed URL is empty, we haven't explored at all yet
 	// kick start with a search with 0 offset
 	if url == "" {
 		return []string{}, "", "", errors.New("error: empty url string provided")
 	}
 
-	results, foundInCache := locationCache.Get(url)
-	if !foundInCache {
-		fmt.Println("Not found in cache, calling API...")
-		res, err := http.Get(url)
-		if err != nil {
-			return []string{}, "", "", errors.New("error: Could not GET Location Areas")
-		}
-		defer res.Body.Close()
-
-		results, err = io.ReadAll(res.Body)
-		if err != nil {
-			return []string{}, "", "", errors.New("error: could not read response body")
-		}
-
-		locationCache.Add(url, results)
-	} else {
-		fmt.Println("Using cache on GetLocationAreas...")
+	results, err := c.getCached(url)
+	if err != nil {
+		return []string{}, "", "", fmt.Errorf("error: Could not GET Location Areas: %w", err)
 	}
 
 	var LocationAreas LocationAreasResponse
-	err := json.Unmarshal(results, &LocationAreas)
+	err = json.Unmarshal(results, &LocationAreas)
 	if err != nil {
 		return []string{}, "", "", errors.New("error: could not Unmarshall results from res Reader")
 	}
@@ -97,29 +79,7 @@ type LocationAreaSpecificsResponse struct {
 			URL  string `json:"url"`
 		} `json:"language"`
 	} `json:"names"`
-	PokemonEncounters []struct {
-		Pokemon struct {
-			Name string `json:"name"`
-			URL  string `json:"url"`
-		} `json:"pokemon"`
-		VersionDetails []struct {
-			Version struct {
-				Name string `json:"name"`
-				URL  string `json:"url"`
-			} `json:"version"`
-			MaxChance        int `json:"max_chance"`
-			EncounterDetails []struct {
-				MinLevel        int           `json:"min_level"`
-				MaxLevel        int           `json:"max_level"`
-				ConditionValues []interface{} `json:"condition_values"`
-				Chance          int           `json:"chance"`
-				Method          struct {
-					Name string `json:"name"`
-					URL  string `json:"url"`
-				} `json:"method"`
-			} `json:"encounter_details"`
-		} `json:"version_details"`
-	} `json:"pokemon_encounters"`
+	PokemonEncounters []PokemonEncounter `json:"pokemon_encounters"`
 }
 
 type PokemonEncounter struct {
@@ -127,49 +87,38 @@ type PokemonEncounter struct {
 		Name string `json:"name"`
 		URL  string `json:"url"`
 	} `json:"pokemon"`
-	VersionDetails []struct {
-		Version struct {
+	VersionDetails []EncounterVersion `json:"version_details"`
+}
+
+// EncounterVersion describes how a Pokemon is encountered in one game version.
+type EncounterVersion struct {
+	Version struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"version"`
+	MaxChance        int `json:"max_chance"`
+	EncounterDetails []struct {
+		MinLevel        int           `json:"min_level"`
+		MaxLevel        int           `json:"max_level"`
+		ConditionValues []interface{} `json:"condition_values"`
+		Chance          int           `json:"chance"`
+		Method          struct {
 			Name string `json:"name"`
 			URL  string `json:"url"`
-		} `json:"version"`
-		MaxChance        int `json:"max_chance"`
-		EncounterDetails []struct {
-			MinLevel        int           `json:"min_level"`
-			MaxLevel        int           `json:"max_level"`
-			ConditionValues []interface{} `json:"condition_values"`
-			Chance          int           `json:"chance"`
-			Method          struct {
-				Name string `json:"name"`
-				URL  string `json:"url"`
-			} `json:"method"`
-		} `json:"encounter_details"`
-	} `json:"version_details"`
+		} `json:"method"`
+	} `json:"encounter_details"`
 }
 
-func GetPokemonInArea(areaName string, locationCache *pokecache.Cache) ([]PokemonEncounter, error) {
-	url := "https://pokeapi.co/api/v2/location-area/" + areaName
+func (c *Client) GetPokemonInArea(areaName string) ([]PokemonEncounter, error) {
+	url := c.baseURL + "/location-area/" + areaName
 
-	results, foundInCache := locationCache.Get(url)
-	if !foundInCache {
-		fmt.Println("Not found in cache, calling API...")
-		res, err := http.Get(url)
-		if err != nil {
-			return nil, fmt.Errorf("error: Could not get details for area %v", areaName)
-		}
-		defer res.Body.Close()
-
-		results, err = io.ReadAll(res.Body)
-		if err != nil {
-			return nil, errors.New("error: could not read response body")
-		}
-
-		locationCache.Add(url, results)
-	} else {
-		fmt.Println("Using cache...")
+	results, err := c.getCached(url)
+	if err != nil {
+		return nil, fmt.Errorf("error: Could not get details for area %v: %w", areaName, err)
 	}
 
 	var LocationAreaSpecificResponseData LocationAreaSpecificsResponse
-	err := json.Unmarshal(results, &LocationAreaSpecificResponseData)
+	err = json.Unmarshal(results, &LocationAreaSpecificResponseData)
 	if err != nil {
 		return nil, errors.New("error: could not Unmarshall results from res Reader")
 	}
@@ -466,33 +415,100 @@ type Pokemon struct {
 	} `json:"past_abilities"`
 }
 
-func GetPokemonDetails(name string, cache *pokecache.Cache) (Pokemon, error) {
-	url := "https://pokeapi.co/api/v2/pokemon/" + name
-
-	results, foundInCache := cache.Get(url)
-	if !foundInCache {
-		fmt.Println("Not found in cache, calling API...")
-		res, err := http.Get(url)
-		if err != nil {
-			return Pokemon{}, fmt.Errorf("error: Could not get details for pokemon %s", name)
-		}
-		defer res.Body.Close()
+// GetPokemonDetails returns the details of the named Pokemon, decoding the API response
+// only when the decoded Pokemon isn't already cached.
+func (c *Client) GetPokemonDetails(name string) (Pokemon, error) {
+	url := c.baseURL + "/pokemon/" + name
 
-		results, err = io.ReadAll(res.Body)
-		if err != nil {
-			return Pokemon{}, errors.New("error: could not read response body")
-		}
+	// the decoded Pokemon is only good while it was decoded from the response that is cached now,
+	// so that the response's byte budget, evictions and refreshes apply to it too
+	generation, cached := c.cache.Generation(url)
+	if decoded, ok := c.pokemon.Get(url); ok && cached && decoded.generation == generation {
+		return decoded.Pokemon, nil
+	}
 
-		cache.Add(url, results)
-	} else {
-		fmt.Println("Using cache...")
+	results, err := c.getCached(url)
+	if err != nil {
+		return Pokemon{}, fmt.Errorf("error: Could not get details for pokemon %s: %w", name, err)
 	}
 
 	var pokemonDetails Pokemon
-	err := json.Unmarshal(results, &pokemonDetails)
+	err = json.Unmarshal(results, &pokemonDetails)
 	if err != nil {
 		return Pokemon{}, errors.New("error: could not Unmarshall results from res Reader")
 	}
+	// a response fetched just now, or an expired one, is decoded again next time, as is one
+	// refreshed since the generation was read
+	if cached {
+		c.pokemon.Add(url, decodedPokemon{Pokemon: pokemonDetails, generation: generation})
+	}
 
 	return pokemonDetails, nil
 }
+
+// GetPokemonSpeciesNames returns the name of every Pokemon species.
+func (c *Client) GetPokemonSpeciesNames() ([]string, error) {
+	return c.getAllNames("pokemon-species")
+}
+
+// GetLocationAreaNames returns the name of every location area.
+func (c *Client) GetLocationAreaNames() ([]string, error) {
+	return c.getAllNames("location-area")
+}
+
+// allNamesLimit is more than the number of resources of any kind, so one page holds all of them.
+const allNamesLimit = 100000
+
+// getAllNames returns the names of every resource of a kind, e.g. "pokemon-species".
+func (c *Client) getAllNames(resource string) ([]string, error) {
+	results, err := c.getCached(fmt.Sprintf("%s/%s/?limit=%d&offset=0", c.baseURL, resource, allNamesLimit))
+	if err != nil {
+		return nil, fmt.Errorf("error: could not GET %s names: %w", resource, err)
+	}
+
+	var list LocationAreasResponse // every list endpoint has the same shape
+	if err := json.Unmarshal(results, &list); err != nil {
+		return nil, fmt.Errorf("error: could not Unmarshall %s names: %w", resource, err)
+	}
+	names := make([]string, len(list.Results))
+	for i, result := range list.Results {
+		names[i] = result.Name
+	}
+	return names, nil
+}
+
+// SpriteURL returns the URL of one of the Pokemon's sprites: the front or back view, in its
+// normal or shiny colors. It is "" when PokeAPI has no such sprite.
+func (p Pokemon) SpriteURL(shiny, back bool) string {
+	switch {
+	case shiny && back:
+		return p.Sprites.BackShiny
+	case shiny:
+		return p.Sprites.FrontShiny
+	case back:
+		return p.Sprites.BackDefault
+	default:
+		return p.Sprites.FrontDefault
+	}
+}
+
+// SpriteURLs returns the URLs of every sprite the Pokemon has out of those SpriteURL picks from.
+func (p Pokemon) SpriteURLs() []string {
+	var urls []string
+	for _, url := range []string{p.Sprites.FrontDefault, p.Sprites.FrontShiny, p.Sprites.BackDefault, p.Sprites.BackShiny} {
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// GetSprite returns the PNG image at url, one of a Pokemon's sprite URLs. Sprites are kept in
+// the cache like API responses, so each one is only downloaded once.
+func (c *Client) GetSprite(url string) ([]byte, error) {
+	body, err := c.getCached(url)
+	if err != nil {
+		return nil, fmt.Errorf("error: could not GET sprite %s: %w", url, err)
+	}
+	return body, nil
+}
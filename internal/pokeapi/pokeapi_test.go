@@ -0,0 +1,462 @@
+package pokeapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/mockapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache/pokecachetest"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokestore"
+)
+
+func TestGetLocationAreasMakesOneRequestUnderParallelLoad(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		fmt.Fprint(w, `{"count":1,"next":"","previous":null,"results":[{"name":"canalave-city-area","url":""}]}`)
+	}))
+	defer server.Close()
+
+	cache, _ := pokecache.NewByteCache(5 * time.Second)
+	defer cache.Stop()
+	client, _ := NewClient(cache)
+	defer client.Close()
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names, _, _, err := client.GetLocationAreas(server.URL)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if len(names) != 1 || names[0] != "canalave-city-area" {
+				t.Errorf("unexpected names: %v", names)
+			}
+		}()
+	}
+
+	for cache.Stats().Coalesced < callers-1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected 1 HTTP request, got %d", got)
+	}
+}
+
+func TestGetCachedRevalidatesWithETag(t *testing.T) {
+	var full, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		fmt.Fprint(w, "testdata")
+	}))
+	defer server.Close()
+
+	const interval = time.Minute
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(time.Hour))
+	defer cache.Stop()
+	client, _ := NewClient(cache)
+	defer client.Close()
+
+	if _, err := client.getCached(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock.Advance(interval * 2)
+	body, err := client.getCached(server.URL)
+	if err != nil || string(body) != "testdata" {
+		t.Fatalf("unexpected result %q, %v", body, err)
+	}
+
+	// the expired entry is refreshed in the background
+	for cache.Stats().Renewals == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if full.Load() != 1 || notModified.Load() != 1 {
+		t.Errorf("expected 1 full response and 1 not modified, got %d and %d", full.Load(), notModified.Load())
+	}
+}
+
+// newTestClient returns a Client whose requests go to server.
+func newTestClient(t *testing.T, server *httptest.Server, opts ...ClientOption) *Client {
+	t.Helper()
+	cache, _ := pokecache.NewByteCache(time.Minute)
+	t.Cleanup(func() { cache.Stop() })
+	client, _ := NewClient(cache, opts...)
+	t.Cleanup(client.Close)
+	client.baseURL = server.URL
+	return client
+}
+
+func TestClientAgainstMockAPI(t *testing.T) {
+	handler, err := mockapi.NewHandler(mockapi.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	cache, _ := pokecache.NewByteCache(time.Minute)
+	defer cache.Stop()
+	client, _ := NewClient(cache, WithBaseURL(server.URL+"/api/v2/"))
+	defer client.Close()
+
+	areas, next, prev, err := client.GetLocationAreas(client.LocationAreasURL(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(areas) != 5 || next == "" || prev != "" {
+		t.Fatalf("got %d areas, next %q, previous %q", len(areas), next, prev)
+	}
+	if _, _, _, err := client.GetLocationAreas(next); err != nil {
+		t.Fatalf("following next: %v", err)
+	}
+
+	encounters, err := client.GetPokemonInArea(areas[0])
+	if err != nil || len(encounters) == 0 {
+		t.Fatalf("GetPokemonInArea(%s) = %v, %v", areas[0], encounters, err)
+	}
+	pokemon, err := client.GetPokemonDetails("pikachu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pokemon.ID != 25 || pokemon.BaseExperience == 0 {
+		t.Errorf("got pikachu %d with base experience %d", pokemon.ID, pokemon.BaseExperience)
+	}
+	if _, err := client.GetPokemonDetails("mewtwo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing Pokemon: got %v, want ErrNotFound", err)
+	}
+
+	species, err := client.GetPokemonSpeciesNames()
+	if err != nil || len(species) != 12 {
+		t.Errorf("GetPokemonSpeciesNames() = %d names, %v", len(species), err)
+	}
+	allAreas, err := client.GetLocationAreaNames()
+	if err != nil || len(allAreas) != 7 {
+		t.Errorf("GetLocationAreaNames() = %d names, %v", len(allAreas), err)
+	}
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	handler, _ := mockapi.NewHandler(mockapi.Options{})
+	server := httptest.NewServer(handler)
+	dir := t.TempDir()
+
+	recorder, err := NewRecorder(dir, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, server, WithTransport(recorder))
+	client.baseURL = server.URL + "/api/v2"
+	recorded, err := client.GetPokemonDetails("pikachu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	replayer, err := NewRecorder(dir, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, _ := pokecache.NewByteCache(time.Minute)
+	defer cache.Stop()
+	replay, _ := NewClient(cache, WithTransport(replayer), WithBaseURL("http://elsewhere.invalid/api/v2"))
+	defer replay.Close()
+
+	replayed, err := replay.GetPokemonDetails("pikachu")
+	if err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if replayed.ID != recorded.ID || replayed.Name != recorded.Name {
+		t.Errorf("replayed %s (%d), recorded %s (%d)", replayed.Name, replayed.ID, recorded.Name, recorded.ID)
+	}
+	if _, err := replay.GetPokemonDetails("bulbasaur"); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("unrecorded request: got %v, want ErrNoRecording", err)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	server, requests := newFakeAPI(t)
+	client := newTestClient(t, server)
+
+	var last PrefetchProgress
+	failures, err := client.Prefetch(context.Background(), PrefetchOptions{
+		StartURL:    client.LocationAreasURL(2),
+		Concurrency: 2,
+	}, func(p PrefetchProgress) { last = p })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(failures) != 1 || failures[0].Resource != "location-area/area-3" {
+		t.Errorf("expected area-3 to fail, got %v", failures)
+	}
+	want := PrefetchProgress{Areas: 3, AreasDone: 2, Pokemon: 2, PokemonDone: 2, Failures: 1}
+	if last != want {
+		t.Errorf("expected final progress %+v, got %+v", want, last)
+	}
+	if got := requests("/pokemon/pikachu"); got != 1 {
+		t.Errorf("expected pikachu to be fetched once, got %d", got)
+	}
+	if _, ok := client.cache.Get(server.URL + "/pokemon/bulbasaur"); !ok {
+		t.Errorf("expected bulbasaur to be cached")
+	}
+}
+
+func TestPrefetchKeepsEntriesPastTheInterval(t *testing.T) {
+	server, requests := newFakeAPI(t)
+	const interval = time.Minute
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(10*interval))
+	defer cache.Stop()
+	client, _ := NewClient(cache, WithBaseURL(server.URL))
+	defer client.Close()
+
+	_, err := client.Prefetch(context.Background(), PrefetchOptions{
+		StartURL:    client.LocationAreasURL(2),
+		Concurrency: 2,
+		TTL:         24 * time.Hour,
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache.Add("not-prefetched", []byte("testdata"))
+
+	// well past the interval and the stale grace, the reapLoop removes the entry that wasn't prefetched
+	clock.Advance(time.Hour)
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.Stats().Expirations == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the entry that wasn't prefetched to be reaped, got %v", cache.List())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for _, url := range []string{
+		client.LocationAreasURL(2),
+		server.URL + "/location-area/area-1",
+		server.URL + "/pokemon/pikachu",
+		server.URL + "/pokemon/bulbasaur",
+	} {
+		if _, ok := cache.Get(url); !ok {
+			t.Errorf("expected %s to still be cached after an hour", url)
+		}
+	}
+	if _, err := client.GetPokemonInArea("area-1"); err != nil || requests("/location-area/area-1") != 1 {
+		t.Errorf("expected area-1 to come from the cache, got %v after %d requests", err, requests("/location-area/area-1"))
+	}
+}
+
+func TestEvictAndClearDropDecodedPokemon(t *testing.T) {
+	server, requests := newFakeAPI(t)
+	client := newTestClient(t, server)
+	url := server.URL + "/pokemon/pikachu"
+
+	get := func(step string, expectedRequests int) {
+		t.Helper()
+		if _, err := client.GetPokemonDetails("pikachu"); err != nil {
+			t.Fatalf("%s: unexpected error: %v", step, err)
+		}
+		if got := requests("/pokemon/pikachu"); got != expectedRequests {
+			t.Errorf("%s: expected %d requests for pikachu, got %d", step, expectedRequests, got)
+		}
+	}
+	get("first time", 1)
+	get("decoded", 1)
+
+	if !client.Evict(url) {
+		t.Errorf("expected Evict to find %s", url)
+	}
+	get("after Evict", 2)
+
+	if removed := client.Clear(); removed != 1 {
+		t.Errorf("expected Clear to remove 1 response, got %d", removed)
+	}
+	get("after Clear", 3)
+
+	// the response going, e.g. to stay within the byte budget, takes the decoded Pokemon with it
+	client.cache.Evict(url)
+	get("after the response was evicted", 4)
+}
+
+func TestRefreshReplacesDecodedPokemon(t *testing.T) {
+	var baseExperience atomic.Int32
+	baseExperience.Store(112)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name":"pikachu","base_experience":%d}`, baseExperience.Load())
+	}))
+	defer server.Close()
+
+	const interval = time.Minute
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(time.Hour))
+	defer cache.Stop()
+	client, _ := NewClient(cache, WithBaseURL(server.URL))
+	defer client.Close()
+
+	for range 2 { // the second lookup is served decoded
+		if p, err := client.GetPokemonDetails("pikachu"); err != nil || p.BaseExperience != 112 {
+			t.Fatalf("expected base experience 112, got %d, %v", p.BaseExperience, err)
+		}
+	}
+
+	// the API changes pikachu, and the expired response is refreshed in the background
+	baseExperience.Store(200)
+	clock.Advance(interval * 2)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p, err := client.GetPokemonDetails("pikachu")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.BaseExperience == 200 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the refreshed base experience 200, still got %d", p.BaseExperience)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSyncThenOffline(t *testing.T) {
+	server, requests := newFakeAPI(t)
+	store, _ := pokestore.Open(t.TempDir())
+
+	client := newTestClient(t, server)
+	failures, err := client.Sync(context.Background(), store, PrefetchOptions{
+		StartURL:    client.LocationAreasURL(2),
+		Concurrency: 2,
+	}, nil)
+	if err != nil || len(failures) != 1 {
+		t.Fatalf("expected sync to finish with area-3 failing, got %v %v", failures, err)
+	}
+	if _, ok := store.SyncedAt(); ok {
+		t.Errorf("expected a sync with failures not to be marked as synced")
+	}
+
+	offline := newTestClient(t, server, WithOffline(store))
+	before := requests("/pokemon/pikachu")
+	pikachu, err := offline.GetPokemonDetails("pikachu")
+	if err != nil {
+		t.Errorf("expected pikachu to be served from the snapshot, got %v", err)
+	}
+	if sprite, err := offline.GetSprite(pikachu.SpriteURL(false, false)); err != nil || string(sprite) != "\x89PNG pikachu" {
+		t.Errorf("expected pikachu's sprite to be served from the snapshot, got %q, %v", sprite, err)
+	}
+	if got := requests("/pokemon/pikachu"); got != before {
+		t.Errorf("expected no requests while offline, got %d more", got-before)
+	}
+	if names, _, _, err := offline.GetLocationAreas(offline.LocationAreasURL(2)); err != nil || len(names) != 2 {
+		t.Errorf("expected first page of areas from the snapshot, got %v %v", names, err)
+	}
+	if names, err := offline.GetLocationAreaNames(); err != nil || len(names) == 0 {
+		t.Errorf("expected every area name from the snapshot, got %v %v", names, err)
+	}
+	if names, err := offline.GetPokemonSpeciesNames(); err != nil || len(names) != 2 {
+		t.Errorf("expected every species name from the snapshot, got %v %v", names, err)
+	}
+
+	_, err = offline.GetPokemonDetails("mew")
+	if !errors.Is(err, pokestore.ErrNotSynced) || !strings.Contains(err.Error(), "run \"sync\"") {
+		t.Errorf("expected a clear not synced error for mew, got %v", err)
+	}
+}
+
+// newFakeAPI starts a server with three location areas over two pages, of which area-3 is broken,
+// and two Pokemon, which are also the only species. Only pikachu has a sprite. The returned function counts the requests made for a path.
+func newFakeAPI(t *testing.T) (*httptest.Server, func(path string) int) {
+	t.Helper()
+	var server *httptest.Server
+	var mu sync.Mutex
+	requests := map[string]int{}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/location-area/":
+			if r.URL.Query().Get("offset") == "2" {
+				fmt.Fprint(w, `{"next":null,"results":[{"name":"area-3"}]}`)
+				return
+			}
+			fmt.Fprintf(w, `{"next":"%s/location-area/?limit=2&offset=2","results":[{"name":"area-1"},{"name":"area-2"}]}`, server.URL)
+		case "/location-area/area-1":
+			fmt.Fprint(w, `{"pokemon_encounters":[{"pokemon":{"name":"pikachu"}},{"pokemon":{"name":"bulbasaur"}}]}`)
+		case "/location-area/area-2":
+			fmt.Fprint(w, `{"pokemon_encounters":[{"pokemon":{"name":"pikachu"}}]}`)
+		case "/pokemon-species/":
+			fmt.Fprint(w, `{"next":null,"results":[{"name":"pikachu"},{"name":"bulbasaur"}]}`)
+		case "/pokemon/pikachu":
+			fmt.Fprintf(w, `{"name":"test","sprites":{"front_default":"%s/sprites/25.png"}}`, server.URL)
+		case "/pokemon/bulbasaur":
+			fmt.Fprint(w, `{"name":"test"}`)
+		case "/sprites/25.png":
+			fmt.Fprint(w, "\x89PNG pikachu")
+		default:
+			http.Error(w, "oops", http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+}
+
+func TestGetSprite(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/sprites/pokemon/shiny/25.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "\x89PNG pikachu")
+	}))
+	defer server.Close()
+	client := newTestClient(t, server)
+
+	var pikachu Pokemon
+	pikachu.Sprites.FrontDefault = server.URL + "/sprites/pokemon/25.png"
+	pikachu.Sprites.FrontShiny = server.URL + "/sprites/pokemon/shiny/25.png"
+	if got := pikachu.SpriteURL(false, true); got != "" {
+		t.Errorf("pikachu has no back sprite, got %q", got)
+	}
+
+	for range 2 {
+		body, err := client.GetSprite(pikachu.SpriteURL(true, false))
+		if err != nil || string(body) != "\x89PNG pikachu" {
+			t.Fatalf("got %q, %v", body, err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("the sprite was downloaded %d times, expected once", got)
+	}
+	if _, err := client.GetSprite(pikachu.SpriteURL(false, false)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a missing sprite: got %v, expected ErrNotFound", err)
+	}
+}
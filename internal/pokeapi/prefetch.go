@@ -0,0 +1,212 @@
+package pokeapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokestore"
+)
+
+// PrefetchOptions controls what Client.Prefetch loads.
+type PrefetchOptions struct {
+	StartURL    string // first page of location areas to walk
+	MaxAreas    int    // stop after this many location areas, 0 for all of them
+	Concurrency int    // most requests to have in flight at once, at least 1
+	// TTL is how long the prefetched responses stay fresh in the cache, so they outlast the
+	// cache's usual interval, or 0 to keep them for the usual interval.
+	TTL time.Duration
+	// Sprites also loads every sprite of each Pokemon, see Pokemon.SpriteURLs.
+	Sprites bool
+}
+
+// PrefetchProgress says how far a Prefetch has got.
+// Totals grow as more location areas and Pokemon are discovered.
+type PrefetchProgress struct {
+	Areas       int
+	AreasDone   int
+	Pokemon     int
+	PokemonDone int
+	Failures    int
+}
+
+// PrefetchFailure is a resource that Prefetch could not load.
+type PrefetchFailure struct {
+	Resource string // e.g. "location-area/canalave-city-area" or "pokemon/pikachu"
+	Err      error
+}
+
+// Prefetch walks the location areas starting at opts.StartURL, the Pokemon encountered in each
+// area and the details of each of those Pokemon, so they are all in the cache before they are needed.
+// progress, if not nil, is called after every resource; calls are never concurrent.
+// Resources that fail to load are returned rather than stopping the walk. The error is only
+// set if the walk could not get started or ctx was cancelled.
+func (c *Client) Prefetch(ctx context.Context, opts PrefetchOptions, progress func(PrefetchProgress)) ([]PrefetchFailure, error) {
+	if opts.Concurrency < 1 {
+		return nil, errors.New("error: prefetch concurrency must be at least 1")
+	}
+
+	p := &prefetcher{client: c, ctx: ctx, progress: progress, ttl: opts.TTL, seen: make(map[string]bool)}
+
+	// pages are walked one after another since each one holds the link to the next
+	var areas []string
+	for url := opts.StartURL; url != ""; {
+		if err := ctx.Err(); err != nil {
+			return p.failures, err
+		}
+		names, next, _, err := c.GetLocationAreas(url)
+		if err != nil {
+			if len(areas) == 0 {
+				return nil, err
+			}
+			p.fail("location-area page "+url, err)
+			break
+		}
+		p.pin(url)
+		areas = append(areas, names...)
+		url = next
+		if opts.MaxAreas > 0 && len(areas) >= opts.MaxAreas {
+			areas = areas[:opts.MaxAreas]
+			break
+		}
+	}
+	p.update(func(pr *PrefetchProgress) { pr.Areas = len(areas) })
+
+	var pokemon []string
+	p.each(areas, opts.Concurrency, func(area string) {
+		encounters, err := c.GetPokemonInArea(area)
+		if err != nil {
+			p.fail("location-area/"+area, err)
+			return
+		}
+		p.pin(c.baseURL + "/location-area/" + area)
+		p.update(func(pr *PrefetchProgress) {
+			for _, e := range encounters {
+				if !p.seen[e.Pokemon.Name] {
+					p.seen[e.Pokemon.Name] = true
+					pokemon = append(pokemon, e.Pokemon.Name)
+					pr.Pokemon++
+				}
+			}
+			pr.AreasDone++
+		})
+	})
+
+	p.each(pokemon, opts.Concurrency, func(name string) {
+		details, err := c.GetPokemonDetails(name)
+		if err != nil {
+			p.fail("pokemon/"+name, err)
+			return
+		}
+		p.pin(c.baseURL + "/pokemon/" + name)
+		if opts.Sprites {
+			for _, url := range details.SpriteURLs() {
+				if _, err := c.GetSprite(url); err != nil {
+					p.fail("sprite "+url, err)
+					continue
+				}
+				p.pin(url)
+			}
+		}
+		p.update(func(pr *PrefetchProgress) { pr.PokemonDone++ })
+	})
+
+	return p.failures, ctx.Err()
+}
+
+// Sync saves everything Prefetch would load into store, so that a Client created WithOffline(store)
+// can serve it later without the network, along with each Pokemon's sprites and the lists of
+// every location area and Pokemon species name. Responses that are already cached are saved without calling the API
+// again. The snapshot is only marked as synced when nothing failed.
+func (c *Client) Sync(ctx context.Context, store *pokestore.Store, opts PrefetchOptions, progress func(PrefetchProgress)) ([]PrefetchFailure, error) {
+	if c.Offline() {
+		return nil, errors.New("error: can't sync while offline")
+	}
+
+	syncer := *c
+	syncer.saveTo = store
+	// an empty decoded cache makes every Pokemon go through getCached, where it is saved
+	pokemon, err := pokecache.NewCache[string, decodedPokemon](c.cache.Interval())
+	if err != nil {
+		return nil, err
+	}
+	defer pokemon.Stop()
+	syncer.pokemon = pokemon
+
+	// sprites are fetched too, so that sprite and "inspect --sprite" work offline
+	opts.Sprites = true
+	failures, err := syncer.Prefetch(ctx, opts, progress)
+	if err != nil {
+		return failures, err
+	}
+	// the full name lists behind "did you mean" suggestions and the TUI's list of areas
+	for _, resource := range []string{"location-area", "pokemon-species"} {
+		if _, err := syncer.getAllNames(resource); err != nil {
+			failures = append(failures, PrefetchFailure{Resource: resource + " names", Err: err})
+		}
+	}
+	if len(failures) == 0 {
+		err = store.MarkSynced(time.Now())
+	}
+	return failures, err
+}
+
+// prefetcher holds the shared state of a running Prefetch.
+type prefetcher struct {
+	client   *Client
+	ctx      context.Context
+	progress func(PrefetchProgress)
+	ttl      time.Duration // see PrefetchOptions.TTL
+
+	mu       sync.Mutex // protects everything below
+	current  PrefetchProgress
+	failures []PrefetchFailure
+	seen     map[string]bool // Pokemon names already queued
+}
+
+// each calls fn for every item, running at most concurrency calls at once.
+// It stops starting new calls once the context is cancelled.
+func (p *prefetcher) each(items []string, concurrency int, fn func(string)) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		if p.ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(item)
+		}()
+	}
+	wg.Wait()
+}
+
+// pin gives the cached response for url the prefetch TTL, if there is one.
+func (p *prefetcher) pin(url string) {
+	if p.ttl > 0 {
+		p.client.cache.SetTTL(url, p.ttl)
+	}
+}
+
+// update changes the progress under the lock and reports it.
+func (p *prefetcher) update(change func(*PrefetchProgress)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	change(&p.current)
+	if p.progress != nil {
+		p.progress(p.current)
+	}
+}
+
+// fail records a resource that could not be loaded.
+func (p *prefetcher) fail(resource string, err error) {
+	p.update(func(pr *PrefetchProgress) {
+		p.failures = append(p.failures, PrefetchFailure{Resource: resource, Err: err})
+		pr.Failures++
+	})
+}
@@ -1,111 +1,771 @@
 package pokecache
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"hash/maphash"
+	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Cache holds our cached data, a mutex for concurrent access, and a channel to stop the reapLoop.
-type Cache struct {
-	cacheMap map[string]cacheEntry
-	mu       sync.RWMutex  // mutex to protect the map across goroutines
-	stopCh   chan struct{} // Channel to signal the reapLoop to stop
-	interval time.Duration // Stores the interval for the reapLoop
+// Cache holds our cached data, split into shards that each have a mutex for concurrent access,
+// and a channel to stop the reapLoop.
+// It maps keys of type K to values of type V. Caching decoded values rather than raw bytes
+// saves decoding them again on every hit.
+type Cache[K comparable, V any] struct {
+	shards   []*shard[K, V] // a single shard unless WithShards asks for more
+	seed     maphash.Seed   // picks the shard for a key
+	stopCh   chan struct{}  // Channel to signal the reapLoop to stop
+	interval time.Duration  // Stores the interval for the reapLoop
+
+	reaper   sync.WaitGroup // tracks the reapLoop goroutine so Stop can wait for it
+	stopOnce sync.Once      // makes closing stopCh and flushing happen only once
+	flushed  chan struct{}  // closed once the flush after stopping has finished
+	flushErr error          // result of the flush, set before flushed is closed
+
+	options // optional behaviour set by the Options passed to NewCache
+
+	// counters are atomic so Get can update them while only holding a shard's read lock
+	hits        atomic.Uint64
+	staleHits   atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	coalesced   atomic.Uint64
+	renewals    atomic.Uint64
+
+	generations atomic.Uint64 // the last generation given to an entry
+
+	budgetMu sync.Mutex // makes only one Add at a time evict entries to stay within the byte budget
+
+	inflightMu sync.Mutex     // protects inflight, separate from the shards so fetches don't block readers
+	inflight   map[K]*call[V] // fetches currently running in GetOrFetch, by key
+}
+
+// ByteCache is the cache of raw response bodies keyed by URL.
+type ByteCache = Cache[string, []byte]
+
+// call is a fetch started by GetOrFetch that other callers for the same key can wait on.
+type call[V any] struct {
+	done chan struct{} // closed once val and err are set
+	val  V
+	err  error
 }
 
 // cacheEntry represents a single item in the cache.
-type cacheEntry struct {
-	createdAt time.Time // A time.Time that represents when the entry was created.
-	val       []byte    // A []byte that represents the raw data we're caching.
+type cacheEntry[V any] struct {
+	createdAt  time.Time     // A time.Time that represents when the entry was created.
+	val        V             // The data we're caching, e.g. the raw []byte of a response.
+	size       int           // Size of val in bytes as stored, see sizeOf.
+	rawSize    int           // Size of val in bytes before compression.
+	packed     []byte        // The gzipped value, set instead of val when the value was compressed.
+	validators Validators    // How to ask the source whether val has changed, if it told us.
+	ttl        time.Duration // How long the entry stays fresh if SetTTL was used, otherwise 0 for the cache interval.
+	generation uint64        // Changes every time the entry is stored or renewed, see Generation.
+}
+
+// Sizer can be implemented by cached values that know roughly how many bytes they take up.
+// []byte and string values are measured by their length; values of other types that don't
+// implement Sizer count as zero bytes in Stats.
+type Sizer interface {
+	Size() int
+}
+
+// sizeOf returns the size of a cached value in bytes.
+func sizeOf(val any) int {
+	switch v := val.(type) {
+	case []byte:
+		return len(v)
+	case string:
+		return len(v)
+	case Sizer:
+		return v.Size()
+	default:
+		return 0
+	}
+}
+
+// Validators are the HTTP response headers that let a client ask the server whether
+// a response it already holds is still current, instead of downloading it again.
+type Validators struct {
+	ETag         string // sent back as If-None-Match
+	LastModified string // sent back as If-Modified-Since
+}
+
+// FetchResult is what a FetchFunc got back from the source.
+type FetchResult[V any] struct {
+	Val        V
+	Validators Validators
+	// NotModified means the source confirmed the cached value is still current.
+	// The cached entry is renewed and Val is ignored.
+	NotModified bool
+}
+
+// FetchFunc fetches the value for a key on behalf of GetOrFetch.
+// prev holds the validators stored with the value currently cached for the key, which are
+// empty when nothing is cached, so the source can answer NotModified instead of resending it.
+type FetchFunc[V any] func(prev Validators) (FetchResult[V], error)
+
+// Stats is a point-in-time snapshot of the cache's activity and size.
+type Stats struct {
+	Hits        uint64 // Get calls that found their key
+	StaleHits   uint64 // GetOrFetch calls answered with an expired entry
+	Misses      uint64 // Get calls that did not find their key
+	Evictions   uint64 // entries removed on request by Evict or Clear, or to stay within the byte budget
+	Expirations uint64 // entries removed by the reapLoop because they got too old
+	Coalesced   uint64 // GetOrFetch misses that waited on another caller's fetch instead of fetching
+	Renewals    uint64 // expired entries renewed because the source said they had not changed
+	Entries     int    // number of entries currently cached
+	Bytes       int    // total size in bytes of the cached values as stored, i.e. after compression
+	RawBytes    int    // total size in bytes of the cached values before compression
+	Compressed  int    // number of entries stored compressed
+}
+
+// CompressionRatio is how many times smaller compression has made the cached values.
+// It is 1 when nothing is cached or nothing was compressed.
+func (s Stats) CompressionRatio() float64 {
+	if s.Bytes == 0 {
+		return 1
+	}
+	return float64(s.RawBytes) / float64(s.Bytes)
+}
+
+// EntryInfo describes a single cached entry without exposing its value.
+type EntryInfo[K comparable] struct {
+	Key   K
+	Size  int // size as stored, i.e. after compression
+	Age   time.Duration
+	Stale bool // the entry has expired and is only kept for the stale grace period
+}
+
+// options holds the optional Cache behaviour. It doesn't depend on the key and value
+// types so the same Options work for every kind of Cache.
+type options struct {
+	// staleGrace is how long entries are kept after they expire so they can still be served
+	// while a fresh copy is fetched, or when fetching fails.
+	staleGrace time.Duration
+
+	// flush is called once the cache has stopped, so a persistent tier can write out what it holds.
+	flush func() error
+
+	// logger receives diagnostic messages. Nothing is logged when it is nil.
+	logger *log.Logger
+
+	// clock provides the current time and the reapLoop's ticker.
+	clock Clock
+
+	// shards is the number of independently locked parts the entries are split into.
+	shards int
+
+	// compressAbove is the size in bytes above which []byte values are stored gzipped.
+	// Zero turns compression off.
+	compressAbove int
+
+	// maxBytes is the byte budget for the stored values. Zero means no limit.
+	maxBytes int
+}
+
+// Option configures optional Cache behaviour in NewCache.
+type Option func(*options)
+
+// WithStaleGrace keeps expired entries for an extra grace period.
+// During that period GetOrFetch serves the expired value straight away and refreshes it in the
+// background, and falls back to it when the refresh fails, e.g. because the network is down.
+func WithStaleGrace(grace time.Duration) Option {
+	return func(o *options) {
+		o.staleGrace = grace
+	}
+}
+
+// WithFlush sets a function that Stop calls once, after the reapLoop has exited,
+// so that a persistent tier behind the cache can write out anything it still holds.
+func WithFlush(flush func() error) Option {
+	return func(o *options) {
+		o.flush = flush
+	}
+}
+
+// WithLogger sends the cache's diagnostic messages to logger. By default nothing is logged.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithClock makes the cache use clock instead of the real time, e.g. a fake clock in tests.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithShards splits the cache's entries into n shards with a lock each, so that concurrent
+// calls for different keys rarely contend. The reapLoop also works through one shard at a time.
+// A cache has a single shard by default, which is plenty for one user at the REPL.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithCompression stores []byte values larger than threshold bytes gzipped, and unzips them
+// again when they are read. Values of other types are never compressed. Compression is only
+// kept when it actually makes the value smaller.
+func WithCompression(threshold int) Option {
+	return func(o *options) {
+		o.compressAbove = threshold
+	}
+}
+
+// WithMaxBytes limits the total size of the stored values to maxBytes, counting compressed values
+// at their compressed size. When an Add goes over the limit the oldest entries are evicted.
+// A value bigger than the whole limit is not cached at all, rather than emptying the cache.
+func WithMaxBytes(maxBytes int) Option {
+	return func(o *options) {
+		o.maxBytes = maxBytes
+	}
 }
 
 // creates a new cache with a configurable interval (time.Duration)
-func NewCache(interval time.Duration) (*Cache, error) {
+// entries expire once they are older than the interval
+func NewCache[K comparable, V any](interval time.Duration, opts ...Option) (*Cache[K, V], error) {
 	if interval <= 0 {
 		return nil, errors.New("interval must be greater than zero")
 	}
 
-	cache := &Cache{ // Use a pointer literal to initialize the struct
-		cacheMap: make(map[string]cacheEntry),
+	cache := &Cache[K, V]{ // Use a pointer literal to initialize the struct
+		seed:     maphash.MakeSeed(),
 		stopCh:   make(chan struct{}), // Initialize the stop channel
 		interval: interval,
+		flushed:  make(chan struct{}),
+		inflight: make(map[K]*call[V]),
+		options:  options{clock: realClock{}, shards: 1},
+	}
+	for _, opt := range opts {
+		opt(&cache.options)
+	}
+	if cache.clock == nil {
+		return nil, errors.New("clock must not be nil")
+	}
+	if cache.staleGrace < 0 {
+		return nil, errors.New("stale grace must not be negative")
+	}
+	if cache.compressAbove < 0 || cache.maxBytes < 0 {
+		return nil, errors.New("compression threshold and max bytes must not be negative")
+	}
+	if cache.options.shards < 1 {
+		return nil, errors.New("shards must be at least one")
+	}
+	cache.shards = make([]*shard[K, V], cache.options.shards)
+	for i := range cache.shards {
+		cache.shards[i] = newShard[K, V]()
 	}
 
 	// Start the reapLoop in a separate goroutine
-	go cache.reapLoop()
+	// the ticker is created here rather than in reapLoop so no tick can be missed before it starts
+	cache.reaper.Add(1)
+	go cache.reapLoop(cache.clock.NewTicker(interval))
 
 	return cache, nil
 }
 
+// NewByteCache creates a cache of raw bytes keyed by strings, such as response bodies keyed by URL.
+func NewByteCache(interval time.Duration, opts ...Option) (*ByteCache, error) {
+	return NewCache[string, []byte](interval, opts...)
+}
+
+// Interval returns how long entries stay fresh.
+func (c *Cache[K, V]) Interval() time.Duration {
+	return c.interval
+}
+
 // adds a new entry to the cache
-func (c *Cache) Add(key string, val []byte) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *Cache[K, V]) Add(key K, val V) {
+	c.AddWithValidators(key, val, Validators{})
+}
 
+// AddWithValidators adds a new entry to the cache along with the validators
+// that can later be used to check whether it has changed.
+func (c *Cache[K, V]) AddWithValidators(key K, val V, validators Validators) {
 	// create new entry and add to the cache
-	entry := cacheEntry{
-		createdAt: time.Now(),
-		val:       val,
+	entry := c.newEntry(val, validators)
+
+	s := c.shardFor(key)
+	if c.maxBytes > 0 && entry.size > c.maxBytes {
+		c.logf("not caching %v, its %d bytes are more than the cache's limit of %d", key, entry.size, c.maxBytes)
+		// the value it would have replaced is out of date now
+		s.mu.Lock()
+		if old, ok := s.entries[key]; ok {
+			s.remove(key, old)
+		}
+		s.mu.Unlock()
+		return
+	}
+
+	s.mu.Lock()
+	// a refreshed entry keeps the TTL it was given
+	if old, ok := s.entries[key]; ok {
+		entry.ttl = old.ttl
+	}
+	s.put(key, entry)
+	s.mu.Unlock()
+
+	if c.maxBytes > 0 {
+		c.enforceBudget()
+	}
+}
+
+// newEntry creates the entry for val, compressing it if it's a large enough []byte.
+func (c *Cache[K, V]) newEntry(val V, validators Validators) cacheEntry[V] {
+	entry := cacheEntry[V]{
+		createdAt:  c.clock.Now(),
+		val:        val,
+		size:       sizeOf(val),
+		validators: validators,
+		generation: c.generations.Add(1),
+	}
+	entry.rawSize = entry.size
+
+	if b, ok := any(val).([]byte); ok && c.compressAbove > 0 && len(b) > c.compressAbove {
+		packed, err := compress(b)
+		if err != nil {
+			c.logf("could not compress cache entry, storing it as is: %v", err)
+		} else if len(packed) < len(b) {
+			var zero V
+			entry.val = zero
+			entry.packed = packed
+			entry.size = len(packed)
+		}
+	}
+	return entry
+}
+
+// unpack returns the value held by entry, unzipping it if it was stored compressed.
+// It returns false if the value could not be unzipped, which callers treat as a miss.
+func (c *Cache[K, V]) unpack(entry cacheEntry[V]) (V, bool) {
+	if entry.packed == nil {
+		return entry.val, true
+	}
+	b, err := decompress(entry.packed)
+	if err != nil {
+		c.logf("could not decompress cache entry: %v", err)
+		var zero V
+		return zero, false
+	}
+	// only []byte values are ever compressed, see newEntry
+	return any(b).(V), true
+}
+
+// enforceBudget evicts the oldest entries until the stored values fit within maxBytes.
+func (c *Cache[K, V]) enforceBudget() {
+	c.budgetMu.Lock()
+	defer c.budgetMu.Unlock()
+
+	total := c.Stats().Bytes
+	if total <= c.maxBytes {
+		return
+	}
+
+	type candidate struct {
+		key       K
+		createdAt time.Time
+		shard     *shard[K, V]
+	}
+	var candidates []candidate
+	for _, s := range c.shards {
+		s.mu.RLock()
+		for key, entry := range s.entries {
+			candidates = append(candidates, candidate{key, entry.createdAt, s})
+		}
+		s.mu.RUnlock()
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].createdAt.Before(candidates[j].createdAt)
+	})
+
+	for _, cand := range candidates {
+		if total <= c.maxBytes {
+			return
+		}
+		s := cand.shard
+		s.mu.Lock()
+		// skip entries that were replaced or removed since we looked
+		if entry, ok := s.entries[cand.key]; ok && entry.createdAt.Equal(cand.createdAt) {
+			s.remove(cand.key, entry)
+			total -= entry.size
+			c.evictions.Add(1)
+		}
+		s.mu.Unlock()
 	}
-	c.cacheMap[key] = entry
 }
 
 // gets an entry from the cache.
-// It should take a key (a string) and return a []byte and a bool.
+// It should take a key and return the value and a bool.
 // The bool should be true if the entry was found and false if it wasn't.
-func (c *Cache) Get(key string) ([]byte, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// Expired entries kept for the stale grace period are not returned.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	s := c.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if cacheEntry, ok := s.entries[key]; ok && c.isFresh(cacheEntry, c.clock.Now()) {
+		if val, ok := c.unpack(cacheEntry); ok {
+			c.hits.Add(1)
+			return val, true
+		}
+	}
+	c.misses.Add(1)
+	var zero V
+	return zero, false
+}
+
+// Has reports whether there is an entry for key, fresh or kept for the stale grace period,
+// without counting a hit or a miss.
+func (c *Cache[K, V]) Has(key K) bool {
+	s := c.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.entries[key]
+	return ok
+}
+
+// Generation returns a number that changes every time the entry for key is stored, refreshed
+// or renewed, so anything derived from the value can tell whether it is still current.
+// Returns false if there is no fresh entry for the key, since an expired one is about to change.
+func (c *Cache[K, V]) Generation(key K) (uint64, bool) {
+	s := c.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[key]
+	if !ok || !c.isFresh(entry, c.clock.Now()) {
+		return 0, false
+	}
+	return entry.generation, true
+}
+
+// GetOrFetch returns the cached value for key, calling fetch to fill the cache on a miss.
+// Concurrent misses for the same key are collapsed into a single call to fetch, and every
+// caller gets that call's result. Values are only cached when fetch returns no error.
+//
+// The stale result is true when the value returned has expired. That happens during the stale
+// grace period, where the expired value is returned at once while it is refreshed in the
+// background, and when fetch fails but an expired value is still held.
+// Expired entries that fetch reports as NotModified are renewed rather than replaced.
+func (c *Cache[K, V]) GetOrFetch(key K, fetch FetchFunc[V]) (val V, stale bool, err error) {
+	s := c.shardFor(key)
+	s.mu.RLock()
+	entry, found := s.entries[key]
+	s.mu.RUnlock()
+	var cached V
+	if found {
+		cached, found = c.unpack(entry)
+	}
+
+	now := c.clock.Now()
+	switch {
+	case found && c.isFresh(entry, now):
+		c.hits.Add(1)
+		return cached, false, nil
+	case found && !c.isExpired(entry, now):
+		c.staleHits.Add(1)
+		go c.refresh(key, fetch)
+		return cached, true, nil
+	}
+
+	c.misses.Add(1)
+	val, err = c.fetchShared(key, fetch)
+	if err != nil && found {
+		// better out of date data than none at all
+		return cached, true, nil
+	}
+	return val, false, err
+}
+
+// refresh fetches key again in the background for a caller that already has the stale value.
+// Nobody is there to recover a panicking fetch, so the panic is logged instead of crashing the
+// program, and the stale value stays cached.
+func (c *Cache[K, V]) refresh(key K, fetch FetchFunc[V]) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.logf("refreshing %v in the background panicked: %v", key, r)
+		}
+	}()
+	c.fetchShared(key, fetch)
+}
+
+// fetchShared calls fetch and caches the result, unless a fetch for key is already running,
+// in which case it waits for that one and shares its result.
+func (c *Cache[K, V]) fetchShared(key K, fetch FetchFunc[V]) (V, error) {
+	c.inflightMu.Lock()
+	if cl, ok := c.inflight[key]; ok {
+		c.inflightMu.Unlock()
+		c.coalesced.Add(1)
+		<-cl.done
+		return cl.val, cl.err
+	}
+	// another caller may have finished fetching between our lookup and taking the lock
+	if val, ok := c.peek(key); ok {
+		c.inflightMu.Unlock()
+		return val, nil
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.inflight[key] = cl
+	c.inflightMu.Unlock()
+
+	// a fetch that panics still releases the callers waiting on it, who get an error, before the
+	// panic carries on up this caller's stack, or is logged by refresh in the background
+	defer func() {
+		r := recover()
+		if r != nil {
+			cl.err = fmt.Errorf("fetching %v panicked: %v", key, r)
+		}
+		c.inflightMu.Lock()
+		delete(c.inflight, key)
+		c.inflightMu.Unlock()
+		close(cl.done)
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	cl.val, cl.err = c.fetchAndStore(key, fetch)
+	return cl.val, cl.err
+}
+
+// fetchAndStore calls fetch with the validators of any entry held for key and stores the result.
+func (c *Cache[K, V]) fetchAndStore(key K, fetch FetchFunc[V]) (V, error) {
+	s := c.shardFor(key)
+	s.mu.RLock()
+	prev := s.entries[key].validators
+	s.mu.RUnlock()
+
+	var zero V
+	result, err := fetch(prev)
+	if err != nil {
+		return zero, err
+	}
+	if !result.NotModified {
+		c.AddWithValidators(key, result.Val, result.Validators)
+		return result.Val, nil
+	}
 
-	if cacheEntry, ok := c.cacheMap[key]; ok {
-		return cacheEntry.val, true
-	} else {
-		return nil, false
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return zero, fmt.Errorf("%v was not modified but is no longer cached", key)
 	}
+	entry.createdAt = c.clock.Now()
+	entry.generation = c.generations.Add(1)
+	// a 304 may carry updated validators, keep the old ones otherwise
+	if result.Validators != (Validators{}) {
+		entry.validators = result.Validators
+	}
+	s.entries[key] = entry
+	c.renewals.Add(1)
+	if val, ok := c.unpack(entry); ok {
+		return val, nil
+	}
+	return zero, fmt.Errorf("%v was not modified but its cached value could not be read", key)
+}
+
+// peek looks up a fresh entry for key without counting a hit or a miss.
+func (c *Cache[K, V]) peek(key K) (V, bool) {
+	s := c.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[key]
+	if !ok || !c.isFresh(entry, c.clock.Now()) {
+		var zero V
+		return zero, false
+	}
+	return c.unpack(entry)
+}
+
+// isFresh reports whether entry is still younger than its TTL at time now.
+func (c *Cache[K, V]) isFresh(entry cacheEntry[V], now time.Time) bool {
+	return now.Sub(entry.createdAt) < c.ttlOf(entry)
+}
+
+// isExpired reports whether entry is past its TTL and the stale grace period at time now, so
+// it is due to be reaped.
+func (c *Cache[K, V]) isExpired(entry cacheEntry[V], now time.Time) bool {
+	return now.Sub(entry.createdAt) >= c.ttlOf(entry)+c.staleGrace
+}
+
+func (c *Cache[K, V]) ttlOf(entry cacheEntry[V]) time.Duration {
+	if entry.ttl > 0 {
+		return entry.ttl
+	}
+	return c.interval
+}
+
+// SetTTL makes the entry for key stay fresh for ttl after it was added instead of the cache
+// interval, e.g. to keep data loaded ahead of time for longer. The entry keeps its TTL when it
+// is refreshed. Returns false if there is no entry for the key.
+func (c *Cache[K, V]) SetTTL(key K, ttl time.Duration) bool {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return false
+	}
+	entry.ttl = ttl
+	s.entries[key] = entry
+	return true
+}
+
+// Evict removes a single entry from the cache.
+// Returns false if there was no entry for the key.
+func (c *Cache[K, V]) Evict(key K) bool {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return false
+	}
+	s.remove(key, entry)
+	c.evictions.Add(1)
+	return true
 }
 
-// Stop signals the reapLoop to stop and waits for it to finish.
-func (c *Cache) Stop() {
-	close(c.stopCh) // Close the stop channel to signal the reapLoop to exit
-	// In a more complex scenario, you might want to use a sync.WaitGroup
-	// here to wait for the reapLoop goroutine to actually finish before returning.
-	// For this example, closing the channel is sufficient to trigger its exit.
-	fmt.Println("Cache stop signal sent.")
+// Clear removes every entry from the cache and returns how many were removed.
+// Cleared entries are counted as evictions.
+func (c *Cache[K, V]) Clear() int {
+	removed := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		removed += len(s.entries)
+		s.entries = make(map[K]cacheEntry[V])
+		s.bytes, s.rawBytes, s.compressed = 0, 0, 0
+		s.mu.Unlock()
+	}
+	c.evictions.Add(uint64(removed))
+	return removed
+}
+
+// Stats returns a snapshot of the cache counters, entry count and byte size.
+func (c *Cache[K, V]) Stats() Stats {
+	entries, bytes, rawBytes, compressed := 0, 0, 0, 0
+	for _, s := range c.shards {
+		s.mu.RLock()
+		entries += len(s.entries)
+		bytes += s.bytes
+		rawBytes += s.rawBytes
+		compressed += s.compressed
+		s.mu.RUnlock()
+	}
+
+	return Stats{
+		Hits:        c.hits.Load(),
+		StaleHits:   c.staleHits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Coalesced:   c.coalesced.Load(),
+		Renewals:    c.renewals.Load(),
+		Entries:     entries,
+		Bytes:       bytes,
+		RawBytes:    rawBytes,
+		Compressed:  compressed,
+	}
+}
+
+// List describes every entry in the cache, sorted by the keys' string form.
+func (c *Cache[K, V]) List() []EntryInfo[K] {
+	now := c.clock.Now()
+	var entries []EntryInfo[K]
+	for _, s := range c.shards {
+		s.mu.RLock()
+		for key, entry := range s.entries {
+			entries = append(entries, EntryInfo[K]{
+				Key:   key,
+				Size:  entry.size,
+				Age:   now.Sub(entry.createdAt),
+				Stale: !c.isFresh(entry, now),
+			})
+		}
+		s.mu.RUnlock()
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return fmt.Sprint(entries[i].Key) < fmt.Sprint(entries[j].Key)
+	})
+	return entries
+}
+
+// Stop signals the reapLoop to stop, waits for it to finish and then flushes any persistent tier.
+// It is safe to call more than once; later calls wait for the first one and return its result.
+func (c *Cache[K, V]) Stop() error {
+	return c.StopContext(context.Background())
+}
+
+// StopContext is like Stop but gives up waiting when ctx is done, returning ctx.Err().
+// The reapLoop still exits and the flush still runs in the background in that case.
+func (c *Cache[K, V]) StopContext(ctx context.Context) error {
+	c.stopOnce.Do(func() {
+		close(c.stopCh) // Close the stop channel to signal the reapLoop to exit
+		c.logf("cache stop signal sent")
+		go func() {
+			c.reaper.Wait()
+			if c.flush != nil {
+				c.flushErr = c.flush()
+			}
+			close(c.flushed)
+		}()
+	})
+
+	select {
+	case <-c.flushed:
+		return c.flushErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// logf writes a diagnostic message to the logger set with WithLogger, if there is one.
+func (c *Cache[K, V]) logf(format string, args ...any) {
+	if c.logger != nil {
+		c.logger.Printf(format, args...)
+	}
 }
 
 // cache.reapLoop() method that is called when the cache is created (by the NewCache function).
 // Each time an interval (the time.Duration passed to NewCache) passes it should remove any entries that are older than the interval.
+// Entries are kept for the stale grace period on top of the interval, if one was set with WithStaleGrace.
 // This makes sure that the cache doesn't grow too large over time. For example, if the interval is 5 seconds, and an entry was added 7 seconds ago, that entry should be removed.
-// I used a time.Ticker to make this happen.
+// I used a time.Ticker to make this happen, which comes from the cache's Clock.
 // Maps are not thread-safe in Go.
 // You should use a sync.Mutex to lock access to the map when you're adding, getting entries or reaping entries.
+// Each shard has its own lock, and reapShard only takes the write lock to delete entries it already found.
 // It's unlikely that you'll have issues because reaping only happens every ~5 seconds, but it's still possible, so you should make your cache package safe for concurrent use.
-func (c *Cache) reapLoop() {
-
-	ticker := time.NewTicker(c.interval)
+func (c *Cache[K, V]) reapLoop(ticker Ticker) {
+	defer c.reaper.Done()
 	defer ticker.Stop()
 
 	// fmt.Printf("reapLoop started with interval: %v\n", c.interval)
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			// fmt.Println("reapLoop Tick!")
-			c.mu.Lock()
-			currTime := time.Now()
-			for key, cacheEntry := range c.cacheMap {
-				if currTime.Sub(cacheEntry.createdAt) >= c.interval {
-					// fmt.Println("deleting cache entry...")
-					delete(c.cacheMap, key)
-				}
+			// reap one shard at a time so the rest of the cache stays available
+			currTime := c.clock.Now()
+			for _, s := range c.shards {
+				c.reapShard(s, currTime)
 			}
-			c.mu.Unlock()
 
 		case <-c.stopCh:
 			// Received stop signal, exit the loop
-			fmt.Println("reapLoop received stop signal, exiting.")
+			c.logf("reapLoop received stop signal, exiting")
 			return
 		}
 	}
@@ -1,9 +1,18 @@
-package pokecache
+package pokecache_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache/pokecachetest"
 )
 
 func TestAddGet(t *testing.T) {
@@ -24,7 +33,7 @@ func TestAddGet(t *testing.T) {
 
 	for i, c := range cases {
 		t.Run(fmt.Sprintf("Test case %v", i), func(t *testing.T) {
-			cache, _ := NewCache(interval)
+			cache, _ := pokecache.NewByteCache(interval)
 			cache.Add(c.key, c.val)
 			val, ok := cache.Get(c.key)
 			if !ok {
@@ -40,9 +49,10 @@ func TestAddGet(t *testing.T) {
 }
 
 func TestReapLoop(t *testing.T) {
-	const baseTime = 5 * time.Millisecond
-	const waitTime = baseTime + 5*time.Millisecond
-	cache, _ := NewCache(baseTime)
+	const interval = 5 * time.Second
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock))
+	defer cache.Stop()
 	cache.Add("https://example.com", []byte("testdata"))
 
 	_, ok := cache.Get("https://example.com")
@@ -51,11 +61,594 @@ func TestReapLoop(t *testing.T) {
 		return
 	}
 
-	time.Sleep(waitTime)
+	clock.Advance(interval - time.Nanosecond)
+	if _, ok := cache.Get("https://example.com"); !ok {
+		t.Errorf("expected to find key just before it expires")
+		return
+	}
 
+	clock.Advance(time.Nanosecond)
 	_, ok = cache.Get("https://example.com")
 	if ok {
 		t.Errorf("expected to not find key")
 		return
 	}
+
+	// the tick fired by Advance lets the reapLoop remove the expired entry
+	waitFor(t, func() bool { return cache.Stats().Entries == 0 })
+	if stats := cache.Stats(); stats.Expirations != 1 || stats.Bytes != 0 {
+		t.Errorf("expected entry to be expired and counted, got %+v", stats)
+	}
+}
+
+func TestReapLoopKeepsEntriesForStaleGrace(t *testing.T) {
+	const interval = time.Hour
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(interval))
+	defer cache.Stop()
+	cache.Add("https://example.com", []byte("testdata"))
+
+	clock.Advance(interval)
+	if entries := cache.List(); len(entries) != 1 || !entries[0].Stale {
+		t.Errorf("expected stale entry to be kept during the grace period, got %+v", entries)
+	}
+
+	clock.Advance(interval)
+	waitFor(t, func() bool { return cache.Stats().Entries == 0 })
+}
+
+// syncBuffer is a strings.Builder that the cache's goroutines can log to while the test reads it.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestBackgroundRefreshSurvivesAPanickingFetch(t *testing.T) {
+	const interval = time.Hour
+	clock := pokecachetest.NewFakeClock(time.Now())
+	var logs syncBuffer
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(interval),
+		pokecache.WithLogger(log.New(&logs, "", 0)))
+	defer cache.Stop()
+	cache.Add("https://example.com", []byte("testdata"))
+
+	clock.Advance(interval + time.Minute)
+	val, stale, err := cache.GetOrFetch("https://example.com", func(pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		panic("boom")
+	})
+	if err != nil || !stale || string(val) != "testdata" {
+		t.Errorf("expected the stale value at once, got %q, %v, %v", val, stale, err)
+	}
+
+	// the refresh panicked on its own goroutine without taking the program down with it
+	waitFor(t, func() bool { return strings.Contains(logs.String(), "panicked: boom") })
+	if entries := cache.List(); len(entries) != 1 || !entries[0].Stale {
+		t.Errorf("expected the stale entry to be kept, got %+v", entries)
+	}
+}
+
+func TestGeneration(t *testing.T) {
+	const interval = time.Minute
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(time.Hour))
+	defer cache.Stop()
+	if _, ok := cache.Generation("https://example.com"); ok {
+		t.Error("expected no generation before the entry is added")
+	}
+
+	cache.Add("https://example.com", []byte("testdata"))
+	first, ok := cache.Generation("https://example.com")
+	if again, _ := cache.Generation("https://example.com"); !ok || again != first {
+		t.Errorf("expected the same generation until the entry changes, got %d then %d", first, again)
+	}
+	cache.Add("https://example.com", []byte("newer"))
+	if second, _ := cache.Generation("https://example.com"); second == first {
+		t.Errorf("expected a new generation after the entry was replaced, got %d again", second)
+	}
+
+	clock.Advance(interval)
+	if _, ok := cache.Generation("https://example.com"); ok {
+		t.Error("expected no generation for an expired entry")
+	}
+}
+
+func TestSetTTL(t *testing.T) {
+	const interval = time.Minute
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(interval))
+	defer cache.Stop()
+	cache.Add("https://example.com/pinned", []byte("testdata"))
+	cache.Add("https://example.com/other", []byte("testdata"))
+	if !cache.SetTTL("https://example.com/pinned", time.Hour) {
+		t.Fatal("expected SetTTL to find the entry")
+	}
+	if cache.SetTTL("https://example.com/missing", time.Hour) {
+		t.Error("expected SetTTL to report a missing entry")
+	}
+
+	// long past the interval and the grace period, the reapLoop has removed only the other entry
+	clock.Advance(3 * interval)
+	waitFor(t, func() bool { return cache.Stats().Entries == 1 })
+	if _, ok := cache.Get("https://example.com/pinned"); !ok {
+		t.Error("expected the entry with a longer TTL to still be fresh")
+	}
+
+	// refreshing the entry keeps its TTL
+	cache.Add("https://example.com/pinned", []byte("newer"))
+	clock.Advance(3 * interval)
+	if _, ok := cache.Get("https://example.com/pinned"); !ok {
+		t.Error("expected the refreshed entry to keep its TTL")
+	}
+
+	clock.Advance(time.Hour)
+	waitFor(t, func() bool { return cache.Stats().Entries == 0 })
+}
+
+func TestStats(t *testing.T) {
+	cache, _ := pokecache.NewByteCache(5 * time.Second)
+	cache.Add("https://example.com", []byte("testdata"))
+	cache.Add("https://example.com/path", []byte("moretestdata"))
+
+	cache.Get("https://example.com")
+	cache.Get("https://example.com/path")
+	cache.Get("https://example.com/missing")
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("expected 2 hits and 1 miss, got %d hits and %d misses", stats.Hits, stats.Misses)
+	}
+	if stats.Entries != 2 {
+		t.Errorf("expected 2 entries, got %d", stats.Entries)
+	}
+	if stats.Bytes != len("testdata")+len("moretestdata") {
+		t.Errorf("expected %d bytes, got %d", len("testdata")+len("moretestdata"), stats.Bytes)
+	}
+
+	// replacing a value should not double count its size
+	cache.Add("https://example.com", []byte("new"))
+	if got := cache.Stats().Bytes; got != len("new")+len("moretestdata") {
+		t.Errorf("expected %d bytes after replace, got %d", len("new")+len("moretestdata"), got)
+	}
+}
+
+func TestEvictAndClear(t *testing.T) {
+	cache, _ := pokecache.NewByteCache(5 * time.Second)
+	cache.Add("a", []byte("1"))
+	cache.Add("b", []byte("22"))
+	cache.Add("c", []byte("333"))
+
+	if !cache.Evict("a") {
+		t.Errorf("expected evict to find key")
+	}
+	if cache.Evict("a") {
+		t.Errorf("expected second evict to miss")
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("expected evicted key to be gone")
+	}
+	if cache.Has("a") || !cache.Has("b") {
+		t.Errorf("expected Has to see b but not the evicted a")
+	}
+
+	list := cache.List()
+	if len(list) != 2 || list[0].Key != "b" || list[1].Key != "c" {
+		t.Errorf("expected sorted list [b c], got %v", list)
+	}
+
+	if removed := cache.Clear(); removed != 2 {
+		t.Errorf("expected clear to remove 2 entries, got %d", removed)
+	}
+	stats := cache.Stats()
+	if stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("expected empty cache after clear, got %+v", stats)
+	}
+	if stats.Evictions != 3 {
+		t.Errorf("expected 3 evictions, got %d", stats.Evictions)
+	}
+}
+
+func TestGetOrFetchCoalescesConcurrentMisses(t *testing.T) {
+	cache, _ := pokecache.NewByteCache(5 * time.Second)
+
+	const callers = 20
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func(pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		fetches.Add(1)
+		<-release
+		return pokecache.FetchResult[[]byte]{Val: []byte("testdata")}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan []byte, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, _, err := cache.GetOrFetch("https://example.com", fetch)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results <- val
+		}()
+	}
+
+	// let every caller reach the cache before the single fetch completes
+	waitFor(t, func() bool { return cache.Stats().Coalesced == callers-1 })
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("expected 1 fetch, got %d", got)
+	}
+	for val := range results {
+		if string(val) != "testdata" {
+			t.Errorf("expected every caller to get the fetched value, got %q", val)
+		}
+	}
+}
+
+func TestGetOrFetchSurvivesAPanickingFetch(t *testing.T) {
+	cache, _ := pokecache.NewByteCache(5 * time.Second)
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	panicking := func(pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		close(entered)
+		<-release
+		panic("boom")
+	}
+	recovered, waiterErr := make(chan any, 1), make(chan error)
+	go func() {
+		defer func() { recovered <- recover() }()
+		cache.GetOrFetch("https://example.com", panicking)
+	}()
+	<-entered
+	go func() {
+		_, _, err := cache.GetOrFetch("https://example.com", panicking)
+		waiterErr <- err
+	}()
+	waitFor(t, func() bool { return cache.Stats().Coalesced == 1 })
+	close(release)
+
+	if err := <-waiterErr; err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("expected the waiting caller to get the panic as an error, got %v", err)
+	}
+	if r := <-recovered; r != "boom" {
+		t.Errorf("expected the panic to reach the fetching caller, got %v", r)
+	}
+	// the key isn't stuck in flight, so the next caller fetches again
+	val, _, err := cache.GetOrFetch("https://example.com", func(pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		return pokecache.FetchResult[[]byte]{Val: []byte("testdata")}, nil
+	})
+	if err != nil || string(val) != "testdata" {
+		t.Errorf("expected a fresh fetch after the panic, got %q, %v", val, err)
+	}
+}
+
+func TestGetOrFetchDoesNotCacheErrors(t *testing.T) {
+	cache, _ := pokecache.NewByteCache(5 * time.Second)
+
+	_, _, err := cache.GetOrFetch("https://example.com", func(pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		return pokecache.FetchResult[[]byte]{}, errors.New("network down")
+	})
+	if err == nil {
+		t.Errorf("expected fetch error to be returned")
+	}
+	if _, ok := cache.Get("https://example.com"); ok {
+		t.Errorf("expected failed fetch not to be cached")
+	}
+}
+
+func TestTypedCache(t *testing.T) {
+	type pokemon struct {
+		Name   string
+		Height int
+	}
+	cache, err := pokecache.NewCache[string, pokemon](5 * time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Stop()
+
+	cache.Add("pikachu", pokemon{Name: "pikachu", Height: 4})
+	p, ok := cache.Get("pikachu")
+	if !ok || p.Name != "pikachu" || p.Height != 4 {
+		t.Errorf("expected to find decoded pikachu, got %+v %v", p, ok)
+	}
+	if _, ok := cache.Get("raichu"); ok {
+		t.Errorf("expected not to find raichu")
+	}
+	// values that aren't bytes or strings and don't implement Sizer have no size
+	if stats := cache.Stats(); stats.Entries != 1 || stats.Bytes != 0 {
+		t.Errorf("expected 1 entry and 0 bytes, got %+v", stats)
+	}
+}
+
+func TestStopIsIdempotentAndFlushesOnce(t *testing.T) {
+	var flushes atomic.Int32
+	cache, _ := pokecache.NewByteCache(time.Millisecond, pokecache.WithFlush(func() error {
+		flushes.Add(1)
+		return errors.New("disk full")
+	}))
+
+	for range 3 {
+		if err := cache.Stop(); err == nil || err.Error() != "disk full" {
+			t.Errorf("expected flush error from Stop, got %v", err)
+		}
+	}
+	if got := flushes.Load(); got != 1 {
+		t.Errorf("expected 1 flush, got %d", got)
+	}
+}
+
+func TestStopContextGivesUpAtDeadline(t *testing.T) {
+	release := make(chan struct{})
+	cache, _ := pokecache.NewByteCache(time.Minute, pokecache.WithFlush(func() error {
+		<-release
+		return nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := cache.StopContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	close(release)
+	if err := cache.Stop(); err != nil {
+		t.Errorf("expected Stop to finish once the flush completes, got %v", err)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	const interval = time.Minute
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(time.Hour))
+	defer cache.Stop()
+	cache.Add("https://example.com", []byte("olddata"))
+	clock.Advance(interval * 2)
+
+	if _, ok := cache.Get("https://example.com"); ok {
+		t.Errorf("expected Get to ignore expired entry")
+	}
+
+	refreshed := make(chan struct{})
+	val, stale, err := cache.GetOrFetch("https://example.com", func(pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		defer close(refreshed)
+		return pokecache.FetchResult[[]byte]{Val: []byte("newdata")}, nil
+	})
+	if err != nil || !stale || string(val) != "olddata" {
+		t.Errorf("expected stale olddata straight away, got %q stale=%v err=%v", val, stale, err)
+	}
+
+	<-refreshed
+	waitFor(t, func() bool {
+		val, ok := cache.Get("https://example.com")
+		return ok && string(val) == "newdata"
+	})
+}
+
+func TestStaleFallbackWhenFetchFails(t *testing.T) {
+	const interval = time.Minute
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(interval))
+	cache.Add("https://example.com", []byte("olddata"))
+
+	// stopping the reapLoop keeps the entry around past the grace period, as if it hadn't been reaped yet
+	cache.Stop()
+	clock.Advance(interval * 3)
+
+	val, stale, err := cache.GetOrFetch("https://example.com", func(pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		return pokecache.FetchResult[[]byte]{}, errors.New("network down")
+	})
+	if err != nil || !stale || string(val) != "olddata" {
+		t.Errorf("expected stale olddata when fetch fails, got %q stale=%v err=%v", val, stale, err)
+	}
+}
+
+func TestGetOrFetchRenewsNotModifiedEntries(t *testing.T) {
+	const interval = time.Minute
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(interval, pokecache.WithClock(clock), pokecache.WithStaleGrace(time.Hour))
+	defer cache.Stop()
+	cache.AddWithValidators("https://example.com", []byte("testdata"), pokecache.Validators{ETag: `"v1"`})
+	clock.Advance(interval * 2)
+
+	var sent pokecache.Validators
+	refreshed := make(chan struct{})
+	cache.GetOrFetch("https://example.com", func(prev pokecache.Validators) (pokecache.FetchResult[[]byte], error) {
+		defer close(refreshed)
+		sent = prev
+		return pokecache.FetchResult[[]byte]{NotModified: true}, nil
+	})
+	<-refreshed
+
+	if sent.ETag != `"v1"` {
+		t.Errorf("expected stored ETag to be passed to fetch, got %q", sent.ETag)
+	}
+	waitFor(t, func() bool { return cache.Stats().Renewals == 1 })
+	val, ok := cache.Get("https://example.com")
+	if !ok || string(val) != "testdata" {
+		t.Errorf("expected renewed entry to be fresh with the old value, got %q %v", val, ok)
+	}
+}
+
+// waitFor polls cond until it is true, failing the test if that takes too long.
+// It is used to wait for the cache's background goroutines, not for time to pass.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShardedCache(t *testing.T) {
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, err := pokecache.NewByteCache(time.Minute, pokecache.WithClock(clock), pokecache.WithShards(8))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cache.Stop()
+
+	const keys = 100
+	for i := range keys {
+		cache.Add(fmt.Sprintf("https://example.com/%d", i), []byte("testdata"))
+	}
+	for i := range keys {
+		if _, ok := cache.Get(fmt.Sprintf("https://example.com/%d", i)); !ok {
+			t.Errorf("expected to find key %d", i)
+		}
+	}
+	if stats := cache.Stats(); stats.Entries != keys || stats.Bytes != keys*len("testdata") {
+		t.Errorf("expected %d entries across shards, got %+v", keys, stats)
+	}
+	if list := cache.List(); len(list) != keys {
+		t.Errorf("expected list of %d entries, got %d", keys, len(list))
+	}
+
+	// every shard is reaped on a tick
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return cache.Stats().Entries == 0 })
+	if got := cache.Stats().Expirations; got != keys {
+		t.Errorf("expected %d expirations, got %d", keys, got)
+	}
+}
+
+func TestNewCacheRejectsZeroShards(t *testing.T) {
+	if _, err := pokecache.NewByteCache(time.Minute, pokecache.WithShards(0)); err == nil {
+		t.Errorf("expected error for zero shards")
+	}
+}
+
+// BenchmarkCacheParallel compares a single map against a sharded cache under a mostly-read
+// load from many goroutines, e.g. go test -bench=CacheParallel -cpu=1,4,16 ./internal/pokecache
+func BenchmarkCacheParallel(b *testing.B) {
+	const keys = 1024
+	urls := make([]string, keys)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://pokeapi.co/api/v2/pokemon/%d", i)
+	}
+	val := []byte("testdata")
+
+	for _, shards := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			cache, _ := pokecache.NewByteCache(time.Hour, pokecache.WithShards(shards))
+			defer cache.Stop()
+			for _, url := range urls {
+				cache.Add(url, val)
+			}
+
+			var next atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := next.Add(1) * 7919
+				for pb.Next() {
+					url := urls[i%keys]
+					// one write for every eight reads
+					if i%8 == 0 {
+						cache.Add(url, val)
+					} else {
+						cache.Get(url)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	cache, _ := pokecache.NewByteCache(time.Minute, pokecache.WithCompression(64))
+	defer cache.Stop()
+
+	big := []byte(strings.Repeat(`{"name":"pikachu","url":"https://pokeapi.co/api/v2/pokemon/25/"}`, 100))
+	small := []byte("testdata")
+	cache.Add("big", big)
+	cache.Add("small", small)
+
+	val, ok := cache.Get("big")
+	if !ok || string(val) != string(big) {
+		t.Errorf("expected compressed value to come back unchanged")
+	}
+	val, ok = cache.Get("small")
+	if !ok || string(val) != string(small) {
+		t.Errorf("expected small value to come back unchanged")
+	}
+
+	stats := cache.Stats()
+	if stats.Compressed != 1 {
+		t.Errorf("expected only the big value to be compressed, got %d", stats.Compressed)
+	}
+	if stats.RawBytes != len(big)+len(small) {
+		t.Errorf("expected %d raw bytes, got %d", len(big)+len(small), stats.RawBytes)
+	}
+	if stats.Bytes >= stats.RawBytes || stats.CompressionRatio() <= 1 {
+		t.Errorf("expected compression to save space, got %+v", stats)
+	}
+
+	cache.Evict("big")
+	if stats := cache.Stats(); stats.Compressed != 0 || stats.Bytes != len(small) || stats.RawBytes != len(small) {
+		t.Errorf("expected accounting to drop the evicted value, got %+v", stats)
+	}
+}
+
+func TestMaxBytesEvictsOldestEntries(t *testing.T) {
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(time.Hour, pokecache.WithClock(clock), pokecache.WithMaxBytes(10), pokecache.WithShards(4))
+	defer cache.Stop()
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Add(key, []byte("1234"))
+		clock.Advance(time.Second)
+	}
+
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("expected the oldest entry to be evicted")
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+	if stats := cache.Stats(); stats.Bytes != 8 || stats.Evictions != 1 {
+		t.Errorf("expected 8 bytes and 1 eviction, got %+v", stats)
+	}
+}
+
+func TestMaxBytesSkipsOversizedEntries(t *testing.T) {
+	clock := pokecachetest.NewFakeClock(time.Now())
+	cache, _ := pokecache.NewByteCache(time.Hour, pokecache.WithClock(clock), pokecache.WithMaxBytes(10))
+	defer cache.Stop()
+	cache.Add("a", []byte("1234"))
+	cache.Add("b", []byte("1234"))
+
+	cache.Add("huge", []byte("12345678901"))
+	if _, ok := cache.Get("huge"); ok {
+		t.Errorf("expected a value over the whole limit not to be cached")
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected %s to survive the oversized Add", key)
+		}
+	}
+	if stats := cache.Stats(); stats.Bytes != 8 || stats.Evictions != 0 {
+		t.Errorf("expected 8 bytes and no evictions, got %+v", stats)
+	}
 }
@@ -0,0 +1,85 @@
+// Package pokecachetest provides helpers for testing code that uses pokecache.
+package pokecachetest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+)
+
+// FakeClock is a pokecache.Clock whose time only moves when Advance is called.
+// Pass it to pokecache.NewCache with pokecache.WithClock to drive expiry and reaping
+// in tests without sleeping.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFakeClock creates a FakeClock set to start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the fake current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a Ticker that ticks as Advance moves the clock past each period.
+func (c *FakeClock) NewTicker(d time.Duration) pokecache.Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTicker{
+		clock:  c,
+		ch:     make(chan time.Time, 1),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward by d and fires any tickers that became due.
+// Like a time.Ticker, a ticker whose last tick hasn't been received yet drops the new one.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		if t.stopped || t.next.After(c.now) {
+			continue
+		}
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// fakeTicker is a Ticker driven by a FakeClock.
+type fakeTicker struct {
+	clock   *FakeClock
+	ch      chan time.Time
+	period  time.Duration
+	next    time.Time // when the ticker is next due, protected by clock.mu
+	stopped bool      // protected by clock.mu
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
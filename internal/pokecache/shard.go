@@ -0,0 +1,84 @@
+package pokecache
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// shard holds part of a Cache's entries behind its own lock, so that calls for keys
+// in different shards don't wait for each other.
+type shard[K comparable, V any] struct {
+	mu      sync.RWMutex // mutex to protect the map across goroutines
+	entries map[K]cacheEntry[V]
+
+	// accounting for the values in entries, protected by mu
+	bytes      int // size as stored, i.e. after compression
+	rawBytes   int // size before compression
+	compressed int // number of entries stored compressed
+}
+
+func newShard[K comparable, V any]() *shard[K, V] {
+	return &shard[K, V]{entries: make(map[K]cacheEntry[V])}
+}
+
+// put stores entry under key, replacing any entry already there. s.mu must be held.
+func (s *shard[K, V]) put(key K, entry cacheEntry[V]) {
+	// replacing an entry must not count its old value twice
+	if old, ok := s.entries[key]; ok {
+		s.remove(key, old)
+	}
+	s.entries[key] = entry
+	s.bytes += entry.size
+	s.rawBytes += entry.rawSize
+	if entry.packed != nil {
+		s.compressed++
+	}
+}
+
+// remove deletes entry, which must be the entry stored under key. s.mu must be held.
+func (s *shard[K, V]) remove(key K, entry cacheEntry[V]) {
+	delete(s.entries, key)
+	s.bytes -= entry.size
+	s.rawBytes -= entry.rawSize
+	if entry.packed != nil {
+		s.compressed--
+	}
+}
+
+// shardFor returns the shard that holds key.
+func (c *Cache[K, V]) shardFor(key K) *shard[K, V] {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+// reapShard removes the entries in s that have expired at time now, see isExpired.
+// Expired keys are found while holding only the read lock, and the write lock is then held
+// just long enough to delete them, so Get calls on the shard are barely held up.
+func (c *Cache[K, V]) reapShard(s *shard[K, V], now time.Time) {
+	var expired []K
+	s.mu.RLock()
+	for key, entry := range s.entries {
+		if c.isExpired(entry, now) {
+			expired = append(expired, key)
+		}
+	}
+	s.mu.RUnlock()
+	if len(expired) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range expired {
+		// the entry may have been replaced since we looked
+		entry, ok := s.entries[key]
+		if !ok || !c.isExpired(entry, now) {
+			continue
+		}
+		s.remove(key, entry)
+		c.expirations.Add(1)
+	}
+}
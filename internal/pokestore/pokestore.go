@@ -0,0 +1,106 @@
+// Package pokestore keeps a snapshot of PokeAPI responses on disk so the Pokedex can be used offline.
+package pokestore
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotSynced is returned by Load for responses that are not in the snapshot.
+var ErrNotSynced = errors.New("not in the offline snapshot")
+
+// syncedFile records when the snapshot was last synced.
+const syncedFile = "synced-at"
+
+// Store is a directory holding one file per saved response.
+// Responses are keyed by URL, ignoring the scheme and host, so a snapshot taken from one
+// PokeAPI server can be used with another.
+type Store struct {
+	dir string
+}
+
+// Open returns the Store in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error: could not create snapshot directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Dir returns the directory the Store keeps its files in.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Save stores the response body for rawURL, replacing any earlier copy.
+func (s *Store) Save(rawURL string, body []byte) error {
+	path, err := s.path(rawURL)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// write to a temporary file first so an interrupted sync never leaves half a response behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load returns the saved response body for rawURL.
+// The error wraps ErrNotSynced if the response was never saved.
+func (s *Store) Load(rawURL string) ([]byte, error) {
+	path, err := s.path(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", rawURL, ErrNotSynced)
+	}
+	return body, err
+}
+
+// MarkSynced records t as the time the snapshot was last completed.
+func (s *Store) MarkSynced(t time.Time) error {
+	return os.WriteFile(filepath.Join(s.dir, syncedFile), []byte(t.UTC().Format(time.RFC3339)), 0o644)
+}
+
+// SyncedAt returns when the snapshot was last completed, and false if it never was.
+func (s *Store) SyncedAt() (time.Time, bool) {
+	b, err := os.ReadFile(filepath.Join(s.dir, syncedFile))
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	return t, err == nil
+}
+
+// path maps a URL to the file it is saved in.
+// e.g. https://pokeapi.co/api/v2/pokemon/pikachu is saved in <dir>/api/v2/pokemon/pikachu.json
+// and https://pokeapi.co/api/v2/location-area/?limit=20&offset=0 in
+// <dir>/api/v2/location-area/_list@limit%3D20%26offset%3D0.json
+func (s *Store) path(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("error: bad url %q: %w", rawURL, err)
+	}
+
+	name := strings.Trim(u.Path, "/")
+	if strings.HasSuffix(u.Path, "/") || name == "" {
+		name += "/_list"
+	}
+	if u.RawQuery != "" {
+		name += "@" + url.QueryEscape(u.Query().Encode())
+	}
+	// Clean removes any ".." so a URL can't point outside the snapshot
+	clean := filepath.Clean("/" + filepath.FromSlash(name))
+	return filepath.Join(s.dir, clean+".json"), nil
+}
@@ -0,0 +1,85 @@
+package pokestore
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		url  string
+		body string
+	}{
+		{
+			url:  "https://pokeapi.co/api/v2/pokemon/pikachu",
+			body: `{"name":"pikachu"}`,
+		},
+		{
+			url:  "https://pokeapi.co/api/v2/location-area/?limit=20&offset=0",
+			body: `{"results":[]}`,
+		},
+		{
+			url:  "https://pokeapi.co/api/v2/location-area/?offset=20&limit=20",
+			body: `{"results":[{"name":"canalave-city-area"}]}`,
+		},
+	}
+	for _, c := range cases {
+		if err := store.Save(c.url, []byte(c.body)); err != nil {
+			t.Fatalf("unexpected error saving %s: %v", c.url, err)
+		}
+	}
+	for _, c := range cases {
+		body, err := store.Load(c.url)
+		if err != nil || string(body) != c.body {
+			t.Errorf("expected %s to load %q, got %q %v", c.url, c.body, body, err)
+		}
+	}
+
+	// the host doesn't matter, only the path and query
+	body, err := store.Load("http://localhost:8080/api/v2/pokemon/pikachu")
+	if err != nil || string(body) != `{"name":"pikachu"}` {
+		t.Errorf("expected snapshot to be usable with another host, got %q %v", body, err)
+	}
+}
+
+func TestLoadNotSynced(t *testing.T) {
+	store, _ := Open(t.TempDir())
+	_, err := store.Load("https://pokeapi.co/api/v2/pokemon/mew")
+	if !errors.Is(err, ErrNotSynced) {
+		t.Errorf("expected ErrNotSynced, got %v", err)
+	}
+}
+
+func TestPathStaysInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := Open(dir)
+	path, err := store.path("https://pokeapi.co/../../etc/passwd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		t.Errorf("expected %s to be inside %s", path, dir)
+	}
+}
+
+func TestSyncedAt(t *testing.T) {
+	store, _ := Open(t.TempDir())
+	if _, ok := store.SyncedAt(); ok {
+		t.Errorf("expected a new snapshot not to be synced")
+	}
+	now := time.Now().Truncate(time.Second)
+	if err := store.MarkSynced(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok := store.SyncedAt(); !ok || !got.Equal(now) {
+		t.Errorf("expected synced at %v, got %v %v", now, got, ok)
+	}
+}
@@ -0,0 +1,106 @@
+// Package sprite draws Pokemon sprites in a terminal.
+//
+// Each character cell shows two pixels, one above the other: the upper half block "▀" is drawn
+// in the top pixel's color on a background of the bottom pixel's color. Transparent pixels show
+// the terminal's own background, so sprites look right on dark and light terminals alike.
+package sprite
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+)
+
+// opaque is the alpha, out of 0xffff, from which a pixel is drawn rather than left transparent.
+const opaque = 0x8000
+
+// Decode reads a PNG sprite and crops away the transparent border around the Pokemon.
+func Decode(data []byte) (image.Image, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error: sprite is not a PNG image: %w", err)
+	}
+	return crop(img), nil
+}
+
+// crop returns the smallest part of img holding all of its visible pixels.
+func crop(img image.Image) image.Image {
+	b := img.Bounds()
+	visible := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if isOpaque(img, x, y) {
+				visible = visible.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if visible.Empty() {
+		return img
+	}
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(visible)
+	}
+	return img
+}
+
+func isOpaque(img image.Image, x, y int) bool {
+	_, _, _, a := img.At(x, y).RGBA()
+	return a >= opaque
+}
+
+func rgb(img image.Image, x, y int) ansi.RGB {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return ansi.RGB{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8)}
+}
+
+// Render draws img as lines of text, each one column per pixel wide and half as many lines
+// as the image is tall. With a disabled palette the sprite is drawn as a silhouette without
+// colors.
+func Render(img image.Image, p ansi.Palette) []string {
+	b := img.Bounds()
+	var lines []string
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		var line strings.Builder
+		current := "" // escape codes in effect, so they are only written when they change
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top := isOpaque(img, x, y)
+			bottom := y+1 < b.Max.Y && isOpaque(img, x, y+1)
+
+			cell, codes := " ", ""
+			switch {
+			case top && bottom:
+				cell = "▀"
+				codes = p.FgCode(rgb(img, x, y)) + p.BgCode(rgb(img, x, y+1))
+				if !p.Enabled {
+					cell = "█"
+				}
+			case top:
+				cell, codes = "▀", p.FgCode(rgb(img, x, y))
+			case bottom:
+				cell, codes = "▄", p.FgCode(rgb(img, x, y+1))
+			}
+
+			if p.Enabled && codes != current {
+				line.WriteString(p.Reset() + codes)
+				current = codes
+			}
+			line.WriteString(cell)
+		}
+		if current != "" {
+			line.WriteString(p.Reset())
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// Width is the number of columns each line from Render takes up.
+func Width(img image.Image) int {
+	return img.Bounds().Dx()
+}
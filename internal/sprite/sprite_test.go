@@ -0,0 +1,80 @@
+package sprite
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+)
+
+// testSprite is a 4x5 PNG with a transparent border around these pixels:
+//
+//	red  red
+//	blue .
+//	.    green
+func testSprite(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 5))
+	img.Set(1, 1, color.NRGBA{R: 255, A: 255})
+	img.Set(2, 1, color.NRGBA{R: 255, A: 255})
+	img.Set(1, 2, color.NRGBA{B: 255, A: 255})
+	img.Set(2, 3, color.NRGBA{G: 255, A: 255})
+	img.Set(0, 4, color.NRGBA{R: 255, A: 40}) // too faint to draw
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestRender(t *testing.T) {
+	img, err := Decode(testSprite(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Width(img) != 2 || img.Bounds().Dy() != 3 {
+		t.Fatalf("cropped to %v, expected 2x3", img.Bounds())
+	}
+
+	cases := []struct {
+		name     string
+		palette  ansi.Palette
+		expected []string
+	}{
+		{
+			name:     "silhouette",
+			expected: []string{"█▀", " ▀"},
+		},
+		{
+			name:    "truecolor",
+			palette: ansi.Palette{Enabled: true, TrueColor: true},
+			expected: []string{
+				"\x1b[0m\x1b[38;2;255;0;0m\x1b[48;2;0;0;255m▀\x1b[0m\x1b[38;2;255;0;0m▀\x1b[0m",
+				" \x1b[0m\x1b[38;2;0;255;0m▀\x1b[0m",
+			},
+		},
+		{
+			name:    "256 colors",
+			palette: ansi.Palette{Enabled: true},
+			expected: []string{
+				"\x1b[0m\x1b[38;5;196m\x1b[48;5;21m▀\x1b[0m\x1b[38;5;196m▀\x1b[0m",
+				" \x1b[0m\x1b[38;5;46m▀\x1b[0m",
+			},
+		},
+	}
+	for _, c := range cases {
+		if got := Render(img, c.palette); !slices.Equal(got, c.expected) {
+			t.Errorf("%s: got %q, expected %q", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestDecodeNotPNG(t *testing.T) {
+	if _, err := Decode([]byte("<html>not found</html>")); err == nil {
+		t.Error("expected an error decoding something that isn't a PNG")
+	}
+}
@@ -0,0 +1,114 @@
+package term
+
+import (
+	"bufio"
+	"unicode"
+)
+
+// key codes for the control keys read from a raw terminal
+const (
+	KeyCtrlA     = 1
+	KeyCtrlB     = 2
+	KeyCtrlC     = 3
+	KeyCtrlD     = 4
+	KeyCtrlE     = 5
+	KeyCtrlF     = 6
+	KeyCtrlG     = 7
+	KeyCtrlH     = 8
+	KeyTab       = 9
+	KeyCtrlK     = 11
+	KeyCtrlL     = 12
+	KeyEnter     = 13
+	KeyCtrlN     = 14
+	KeyCtrlP     = 16
+	KeyCtrlR     = 18
+	KeyCtrlU     = 21
+	KeyCtrlW     = 23
+	KeyEscape    = 27
+	KeyBackspace = 127
+)
+
+// keys that ReadKey turns escape sequences into, outside the range of real runes
+const (
+	KeyUp rune = unicode.MaxRune + 1 + iota
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyDelete
+	KeyPageUp
+	KeyPageDown
+	KeyBackTab
+	KeyUnknown
+)
+
+// ReadKey reads one key press from a terminal in raw mode, turning escape sequences for the
+// arrow, editing and paging keys into single keys, and a line feed into KeyEnter.
+// Escape on its own is told apart from the start of a sequence by nothing else having
+// arrived with it.
+func ReadKey(r *bufio.Reader) (rune, error) {
+	key, _, err := r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if key == '\n' {
+		return KeyEnter, nil
+	}
+	if key != KeyEscape || r.Buffered() == 0 {
+		return key, nil
+	}
+
+	intro, _, err := r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if intro != '[' && intro != 'O' {
+		return KeyUnknown, nil
+	}
+	var params []rune
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			return escapeKey(c, string(params)), nil
+		}
+		params = append(params, c)
+	}
+}
+
+// escapeKey names the key sent as ESC [ params final, or ESC O final.
+func escapeKey(final rune, params string) rune {
+	switch final {
+	case 'A':
+		return KeyUp
+	case 'B':
+		return KeyDown
+	case 'C':
+		return KeyRight
+	case 'D':
+		return KeyLeft
+	case 'H':
+		return KeyHome
+	case 'F':
+		return KeyEnd
+	case 'Z':
+		return KeyBackTab
+	case '~':
+		switch params {
+		case "1", "7":
+			return KeyHome
+		case "4", "8":
+			return KeyEnd
+		case "3":
+			return KeyDelete
+		case "5":
+			return KeyPageUp
+		case "6":
+			return KeyPageDown
+		}
+	}
+	return KeyUnknown
+}
@@ -0,0 +1,22 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+// Package term puts terminals into raw mode, asks them for their size and reads key presses
+// from them, for the line editor and the full-screen interface.
+package term
+
+import "errors"
+
+var errUnsupported = errors.New("raw terminal mode is not supported on this platform")
+
+// MakeRaw is not supported on this platform, so the line editor reads whole lines without editing.
+func MakeRaw(fd int) (restore func() error, err error) {
+	return nil, errUnsupported
+}
+
+func IsTerminal(fd int) bool {
+	return false
+}
+
+func Size(fd int) (width, height int, err error) {
+	return 0, 0, errUnsupported
+}
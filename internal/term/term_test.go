@@ -0,0 +1,21 @@
+package term
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("j\x1b[A\x1b[3~\x1b[6~\x1b[Z\x1bOH\r\n\x1b"))
+	expected := []rune{'j', KeyUp, KeyDelete, KeyPageDown, KeyBackTab, KeyHome, KeyEnter, KeyEnter, KeyEscape}
+	for _, want := range expected {
+		got, err := ReadKey(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("expected key %d, got %d", want, got)
+		}
+	}
+}
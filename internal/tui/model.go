@@ -0,0 +1,456 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/term"
+)
+
+// pane is one of the screen's three columns.
+type pane int
+
+const (
+	paneAreas pane = iota
+	paneEncounters
+	paneDetails
+	paneCount
+)
+
+// list is a scrolling list of names that can be filtered by typing part of a name.
+type list struct {
+	title     string
+	items     []string
+	filter    string
+	shown     []string // the items containing filter
+	cursor    int      // index into shown of the selected item
+	top       int      // index into shown of the first item on screen
+	searching bool     // keys typed go into filter
+}
+
+func (l *list) setItems(items []string) {
+	l.items = items
+	l.filter, l.searching = "", false
+	l.cursor, l.top = 0, 0
+	l.refilter()
+}
+
+func (l *list) setFilter(filter string) {
+	selected, _ := l.selected()
+	l.filter = filter
+	l.refilter()
+	// keep the selection on the same item while it still matches
+	l.cursor = max(0, slices.Index(l.shown, selected))
+	l.top = 0
+}
+
+func (l *list) refilter() {
+	l.shown = l.shown[:0]
+	for _, item := range l.items {
+		if strings.Contains(item, l.filter) {
+			l.shown = append(l.shown, item)
+		}
+	}
+}
+
+func (l *list) selected() (string, bool) {
+	if l.cursor >= len(l.shown) {
+		return "", false
+	}
+	return l.shown[l.cursor], true
+}
+
+func (l *list) move(delta int) {
+	l.cursor = min(max(l.cursor+delta, 0), max(len(l.shown)-1, 0))
+}
+
+// scroll keeps the cursor on screen when the list has room for height items.
+func (l *list) scroll(height int) {
+	if l.cursor < l.top {
+		l.top = l.cursor
+	}
+	if l.cursor >= l.top+height {
+		l.top = l.cursor - height + 1
+	}
+}
+
+// app is the state of the TUI. Everything in it is changed on one goroutine, the one running
+// Run, so loads done in the background hand their results back as functions to apply.
+type app struct {
+	opts Options
+
+	focus      pane
+	areas      list
+	encounters list
+	pokemon    string   // the Pokemon shown in the details pane
+	details    []string // its lines as Options.Details rendered them
+	detailsTop int
+
+	// wantArea and wantPokemon are the latest ones asked for, so results for ones asked for
+	// before them can be thrown away when they arrive late.
+	wantArea    string
+	wantPokemon string
+
+	loading int    // loads still running in the background
+	message string // the latest news for the status bar
+	spin    int    // frame of the activity spinner
+
+	width, height int
+
+	// load runs fetch in the background, then applies what it returns on the app's goroutine.
+	load func(fetch func() func(*app))
+}
+
+func newApp(opts Options) *app {
+	if opts.Details == nil {
+		opts.Details = func(p pokeapi.Pokemon) string { return p.Name }
+	}
+	a := &app{
+		opts:       opts,
+		areas:      list{title: "Areas"},
+		encounters: list{title: "Pokemon"},
+		width:      80,
+		height:     24,
+	}
+	a.load = func(fetch func() func(*app)) { fetch()(a) }
+	return a
+}
+
+// start loads the list of areas.
+func (a *app) start() {
+	a.message = "loading areas…"
+	a.background(func() func(*app) {
+		names, err := a.opts.Client.GetLocationAreaNames()
+		return func(a *app) {
+			if err != nil {
+				a.message = err.Error()
+				return
+			}
+			a.areas.setItems(names)
+			a.message = fmt.Sprintf("%d areas, Enter shows the Pokemon in one", len(names))
+		}
+	})
+}
+
+// background counts a load as running until its result has been applied.
+func (a *app) background(fetch func() func(*app)) {
+	a.loading++
+	a.load(func() func(*app) {
+		apply := fetch()
+		return func(a *app) {
+			a.loading--
+			apply(a)
+		}
+	})
+}
+
+func (a *app) exploreArea(area string) {
+	a.wantArea = area
+	a.message = "exploring " + area + "…"
+	gameVersion := a.opts.GameVersion
+	a.background(func() func(*app) {
+		encounters, err := a.opts.Client.GetPokemonInArea(area)
+		return func(a *app) {
+			if area != a.wantArea {
+				return
+			}
+			if err != nil {
+				a.message = err.Error()
+				return
+			}
+			var names []string
+			for _, e := range encounters {
+				if gameVersion != "" && !slices.ContainsFunc(e.VersionDetails, func(v pokeapi.EncounterVersion) bool { return v.Version.Name == gameVersion }) {
+					continue
+				}
+				names = append(names, e.Pokemon.Name)
+			}
+			a.encounters.title = area
+			a.encounters.setItems(names)
+			a.message = fmt.Sprintf("%d Pokemon in %s", len(names), area)
+			if gameVersion != "" {
+				a.message += " in " + gameVersion
+			}
+		}
+	})
+}
+
+func (a *app) showPokemon(name string) {
+	a.wantPokemon = name
+	a.message = "looking up " + name + "…"
+	a.background(func() func(*app) {
+		p, err := a.opts.Client.GetPokemonDetails(name)
+		return func(a *app) {
+			if name != a.wantPokemon {
+				return
+			}
+			if err != nil {
+				a.message = err.Error()
+				return
+			}
+			a.pokemon = name
+			a.details = strings.Split(a.opts.Details(p), "\n")
+			a.detailsTop = 0
+			a.message = name
+		}
+	})
+}
+
+// catch throws a Pokeball at name once its details are in the cache, so that Options.Catch
+// doesn't hold up the screen waiting for the network.
+func (a *app) catch(name string) {
+	if a.opts.Catch == nil {
+		return
+	}
+	a.message = "throwing a Pokeball at " + name + "…"
+	a.background(func() func(*app) {
+		_, err := a.opts.Client.GetPokemonDetails(name)
+		return func(a *app) {
+			if err != nil {
+				a.message = err.Error()
+				return
+			}
+			a.message = a.opts.Catch(name)
+		}
+	})
+}
+
+// focused returns the list in the focused pane, or nil for the details pane.
+func (a *app) focused() *list {
+	switch a.focus {
+	case paneAreas:
+		return &a.areas
+	case paneEncounters:
+		return &a.encounters
+	}
+	return nil
+}
+
+// handleKey acts on a key press and reports whether the user asked to quit.
+func (a *app) handleKey(key rune) (quit bool) {
+	l := a.focused()
+	if key == term.KeyCtrlC {
+		return true
+	}
+	if l != nil && l.searching {
+		switch {
+		case key == term.KeyEnter:
+			l.searching = false
+			return false
+		case key == term.KeyEscape:
+			l.searching = false
+			l.setFilter("")
+			return false
+		case key == term.KeyBackspace || key == term.KeyCtrlH:
+			if l.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(l.filter)
+				l.setFilter(l.filter[:len(l.filter)-size])
+			}
+			return false
+		case key >= ' ' && key < term.KeyUp:
+			l.setFilter(l.filter + string(key))
+			return false
+		}
+	}
+
+	page := max(a.listHeight()-1, 1)
+	switch key {
+	case 'q':
+		return true
+	case term.KeyTab, term.KeyRight, 'l':
+		a.focus = (a.focus + 1) % paneCount
+	case term.KeyBackTab, term.KeyLeft, 'h':
+		a.focus = (a.focus + paneCount - 1) % paneCount
+	case term.KeyUp, 'k', term.KeyCtrlP:
+		a.moveBy(-1)
+	case term.KeyDown, 'j', term.KeyCtrlN:
+		a.moveBy(1)
+	case term.KeyPageUp:
+		a.moveBy(-page)
+	case term.KeyPageDown:
+		a.moveBy(page)
+	case term.KeyHome, 'g':
+		a.moveBy(-1 << 30)
+	case term.KeyEnd, 'G':
+		a.moveBy(1 << 30)
+	case '/':
+		if l != nil {
+			l.searching = true
+		}
+	case term.KeyEscape:
+		if l != nil {
+			l.setFilter("")
+		}
+	case term.KeyEnter:
+		switch a.focus {
+		case paneAreas:
+			if area, ok := a.areas.selected(); ok {
+				a.exploreArea(area)
+				a.focus = paneEncounters
+			}
+		case paneEncounters:
+			if name, ok := a.encounters.selected(); ok {
+				a.showPokemon(name)
+				a.focus = paneDetails
+			}
+		}
+	case 'c':
+		name, ok := a.encounters.selected()
+		if a.focus == paneDetails {
+			name, ok = a.pokemon, a.pokemon != ""
+		}
+		if ok && a.focus != paneAreas {
+			a.catch(name)
+		}
+	}
+	return false
+}
+
+func (a *app) moveBy(delta int) {
+	if l := a.focused(); l != nil {
+		l.move(delta)
+		return
+	}
+	a.detailsTop = min(max(a.detailsTop+delta, 0), max(len(a.details)-a.listHeight(), 0))
+}
+
+// listHeight is how many lines each pane has for its items: the screen less the title bar,
+// the pane headings and the status bar.
+func (a *app) listHeight() int {
+	return max(a.height-3, 1)
+}
+
+// paneWidths splits the screen between the panes, giving the details pane whatever the lists
+// don't need.
+func (a *app) paneWidths() [paneCount]int {
+	usable := a.width - 2 // the two separators
+	areas := min(max(usable*3/10, 12), 36)
+	encounters := min(max(usable/5, 12), 24)
+	details := max(usable-areas-encounters, 0)
+	return [paneCount]int{areas, encounters, details}
+}
+
+// view draws the whole screen as lines of exactly a.width columns.
+func (a *app) view() []string {
+	p := a.opts.Palette
+	widths := a.paneWidths()
+	height := a.listHeight()
+	a.areas.scroll(height)
+	a.encounters.scroll(height)
+
+	lines := []string{p.Reverse(ansi.Pad(" Pokedex — Tab/←/→ switch pane · ↑/↓ move · / search · Enter open · c catch · q quit", a.width))}
+
+	columns := [paneCount][]string{
+		a.listLines(&a.areas, paneAreas, widths[paneAreas], height),
+		a.listLines(&a.encounters, paneEncounters, widths[paneEncounters], height),
+		a.detailLines(widths[paneDetails], height),
+	}
+	separator := p.Dim("│")
+	for row := 0; row <= height; row++ {
+		cells := make([]string, paneCount)
+		for i := range columns {
+			cells[i] = ansi.Pad(columns[i][row], widths[i])
+		}
+		lines = append(lines, strings.Join(cells, separator))
+	}
+
+	lines = append(lines, p.Reverse(ansi.Pad(a.statusBar(), a.width)))
+	return lines
+}
+
+// heading is the first line of a pane, marking the focused one.
+func (a *app) heading(title string, which pane) string {
+	if a.focus == which {
+		return a.opts.Palette.Bold("▸ " + title)
+	}
+	return "  " + title
+}
+
+// listLines draws a list as a heading and height item lines.
+func (a *app) listLines(l *list, which pane, width, height int) []string {
+	title := l.title
+	switch {
+	case l.searching:
+		title += " /" + l.filter + "▏"
+	case l.filter != "":
+		title += " /" + l.filter
+	}
+	lines := []string{a.heading(title, which)}
+	for i := l.top; i < l.top+height; i++ {
+		if i >= len(l.shown) {
+			lines = append(lines, "")
+			continue
+		}
+		// the cursor and caught marks come first, so they show even without colors
+		cursor, caught := " ", ""
+		if i == l.cursor {
+			cursor = "›"
+		}
+		if which == paneEncounters {
+			caught = "  "
+			if a.opts.Caught != nil && a.opts.Caught(l.shown[i]) {
+				caught = "✓ "
+			}
+		}
+		item := cursor + " " + caught + l.shown[i]
+		if i == l.cursor {
+			item = ansi.Pad(item, width)
+			if a.focus == which {
+				item = a.opts.Palette.Reverse(item)
+			} else {
+				item = a.opts.Palette.Bold(item)
+			}
+		}
+		lines = append(lines, item)
+	}
+	return lines
+}
+
+// detailLines draws the details pane as a heading and height lines of the Pokemon's details.
+func (a *app) detailLines(width, height int) []string {
+	lines := []string{a.heading("Details", paneDetails)}
+	for i := a.detailsTop; i < a.detailsTop+height; i++ {
+		line := ""
+		if i < len(a.details) {
+			line = " " + ansi.Truncate(a.details[i], max(width-1, 0))
+		}
+		lines = append(lines, line)
+	}
+	if a.pokemon == "" && len(a.details) == 0 && height > 1 {
+		lines[2] = a.opts.Palette.Dim(" Pick an area, then a Pokemon")
+	}
+	return lines
+}
+
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// statusBar shows how the cache is doing, what the network is doing and the latest news.
+func (a *app) statusBar() string {
+	var parts []string
+	if a.opts.Cache != nil {
+		s := a.opts.Cache.Stats()
+		parts = append(parts, fmt.Sprintf("cache %d hits, %d misses", s.Hits+s.StaleHits, s.Misses))
+	}
+	if a.opts.Client != nil {
+		net := a.opts.Client.Network()
+		switch {
+		case a.opts.Client.Offline():
+			parts = append(parts, "offline")
+		case net.InFlight > 0:
+			parts = append(parts, fmt.Sprintf("%s %d in flight, %d requests", spinner[a.spin%len(spinner)], net.InFlight, net.Requests))
+		default:
+			parts = append(parts, fmt.Sprintf("network idle, %d requests", net.Requests))
+		}
+	}
+	if a.loading > 0 && (a.opts.Client == nil || a.opts.Client.Network().InFlight == 0) {
+		parts = append(parts, spinner[a.spin%len(spinner)]+" loading")
+	}
+	if a.message != "" {
+		parts = append(parts, a.message)
+	}
+	return " " + strings.Join(parts, " │ ")
+}
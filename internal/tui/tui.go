@@ -0,0 +1,172 @@
+// Package tui is a full-screen terminal interface for browsing the Pokemon world: a pane of
+// location areas, a pane of the Pokemon found in the selected area and a pane of details about
+// the selected Pokemon, with a status bar showing how the cache and the network are doing.
+//
+// Lists are moved through with the arrow keys or j and k, and filtered by typing after "/".
+// Data is loaded in the background through the same pokeapi.Client the REPL uses, so the
+// screen keeps responding while the network is slow.
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/term"
+)
+
+// tick is how often the status bar and the screen size are checked while nothing else happens.
+const tick = 100 * time.Millisecond
+
+// Options are what the TUI shows and how it reaches the world outside it.
+type Options struct {
+	Client      *pokeapi.Client
+	Cache       *pokecache.ByteCache // the Client's cache, whose hits and misses are shown
+	Palette     ansi.Palette
+	GameVersion string // only list Pokemon found in this game, or "" for every game
+
+	// Details renders a Pokemon for the details pane, one line per line of text.
+	Details func(p pokeapi.Pokemon) string
+	// Caught reports whether the Pokemon called name is in the user's Pokedex, to mark it.
+	Caught func(name string) bool
+	// Catch throws a Pokeball at the Pokemon called name and says how it went. Its details are
+	// in the Client's cache by the time it is called.
+	Catch func(name string) string
+	// Notes, when set, is where the Client's logger writes, so its notes can be shown.
+	Notes *Notes
+}
+
+// Notes collects lines written to it, such as a logger's, to show them in the status bar.
+// Anything written straight to the terminal would scribble over the screen.
+type Notes struct {
+	mu   sync.Mutex
+	last string
+}
+
+func (n *Notes) Write(p []byte) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if line := strings.TrimSpace(string(p)); line != "" {
+		n.last = line
+	}
+	return len(p), nil
+}
+
+// take returns the note written since it was last called, if any.
+func (n *Notes) take() string {
+	if n == nil {
+		return ""
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	last := n.last
+	n.last = ""
+	return last
+}
+
+// Run shows the TUI on the terminal in and out until the user quits.
+func Run(in, out *os.File, opts Options) error {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return errors.New("error: the TUI needs a terminal to run in")
+	}
+	restore, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("error: could not take over the terminal: %w", err)
+	}
+	defer restore()
+	// switch to the alternate screen without a cursor, and back again when done
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	done := make(chan struct{})
+	defer close(done)
+	results := make(chan func(*app))
+	a := newApp(opts)
+	a.load = func(fetch func() func(*app)) {
+		go func() {
+			apply := fetch()
+			select {
+			case results <- apply:
+			case <-done:
+			}
+		}()
+	}
+
+	// the reader is left blocked on the terminal when the TUI ends; the program exits soon after
+	keys := make(chan rune)
+	readErr := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(in)
+		for {
+			key, err := term.ReadKey(r)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case keys <- key:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	resize(a, out)
+	a.start()
+	last := ""
+	for {
+		// most ticks change nothing, so only frames that differ are written
+		if f := frame(a); f != last {
+			out.WriteString(f)
+			last = f
+		}
+		select {
+		case key := <-keys:
+			if a.handleKey(key) {
+				return nil
+			}
+		case apply := <-results:
+			apply(a)
+		case <-ticker.C:
+			a.spin++
+			if note := opts.Notes.take(); note != "" {
+				a.message = note
+			}
+			if resize(a, out) {
+				out.WriteString("\x1b[2J")
+				last = ""
+			}
+		case err := <-readErr:
+			return fmt.Errorf("error: could not read keys: %w", err)
+		}
+	}
+}
+
+// resize fits the app to the terminal's current size and reports whether it changed.
+func resize(a *app, out *os.File) bool {
+	width, height, err := term.Size(int(out.Fd()))
+	if err != nil || width <= 0 || height <= 0 || width == a.width && height == a.height {
+		return false
+	}
+	a.width, a.height = width, height
+	return true
+}
+
+// frame is the escape codes that draw the whole screen, each line in its place, so it can be
+// written in one go without flickering.
+func frame(a *app) string {
+	var b strings.Builder
+	for i, line := range a.view() {
+		fmt.Fprintf(&b, "\x1b[%d;1H%s\x1b[K", i+1, line)
+	}
+	return b.String()
+}
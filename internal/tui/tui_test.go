@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/mockapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/term"
+)
+
+// newTestApp returns an app browsing the mock API, which loads everything as soon as it is
+// asked for instead of in the background.
+func newTestApp(t *testing.T, opts Options) *app {
+	t.Helper()
+	handler, err := mockapi.NewHandler(mockapi.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	cache, err := pokecache.NewByteCache(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cache.Stop() })
+	client, err := pokeapi.NewClient(cache, pokeapi.WithBaseURL(server.URL+"/api/v2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	opts.Client, opts.Cache = client, cache
+	a := newApp(opts)
+	a.start()
+	return a
+}
+
+func typeKeys(a *app, keys ...rune) {
+	for _, key := range keys {
+		a.handleKey(key)
+	}
+}
+
+func TestBrowse(t *testing.T) {
+	var caught []string
+	a := newTestApp(t, Options{
+		Details: func(p pokeapi.Pokemon) string { return "name: " + p.Name + "\nheight: tall" },
+		Caught:  func(name string) bool { return slices.Contains(caught, name) },
+		Catch: func(name string) string {
+			caught = append(caught, name)
+			return name + " was caught"
+		},
+	})
+	if len(a.areas.shown) != 7 {
+		t.Fatalf("got areas %v, expected the 7 in the mock API", a.areas.shown)
+	}
+
+	// search as you type narrows the list, and Enter keeps the search
+	typeKeys(a, '/', 'm', 'i', 'n', 'e')
+	if !slices.Equal(a.areas.shown, []string{"oreburgh-mine-1f", "oreburgh-mine-b1f"}) {
+		t.Fatalf("searching for \"mine\" shows %v", a.areas.shown)
+	}
+	typeKeys(a, term.KeyEnter, term.KeyDown, term.KeyDown)
+	if area, _ := a.areas.selected(); area != "oreburgh-mine-b1f" {
+		t.Errorf("moving down selected %q, expected the last match", area)
+	}
+
+	typeKeys(a, term.KeyEnter)
+	if a.focus != paneEncounters || len(a.encounters.shown) == 0 {
+		t.Fatalf("Enter on an area should list its Pokemon, got %v", a.encounters.shown)
+	}
+	if a.encounters.title != "oreburgh-mine-b1f" {
+		t.Errorf("encounters pane is titled %q", a.encounters.title)
+	}
+
+	name, _ := a.encounters.selected()
+	typeKeys(a, term.KeyEnter)
+	if a.focus != paneDetails || a.pokemon != name || !slices.Equal(a.details, []string{"name: " + name, "height: tall"}) {
+		t.Fatalf("Enter on %s showed %q in pane %d", name, a.details, a.focus)
+	}
+
+	typeKeys(a, 'c')
+	if !slices.Equal(caught, []string{name}) || a.message != name+" was caught" {
+		t.Errorf("c caught %v with message %q", caught, a.message)
+	}
+
+	typeKeys(a, term.KeyLeft)
+	if a.focus != paneEncounters {
+		t.Errorf("left went to pane %d", a.focus)
+	}
+	if quit := a.handleKey('q'); !quit {
+		t.Error("q should quit")
+	}
+}
+
+func TestSearchKeys(t *testing.T) {
+	a := newTestApp(t, Options{})
+	typeKeys(a, '/', 'q', 'x')
+	if a.areas.filter != "qx" || len(a.areas.shown) != 0 {
+		t.Errorf("typing while searching should filter, even with q: %q shows %v", a.areas.filter, a.areas.shown)
+	}
+	typeKeys(a, term.KeyBackspace, term.KeyBackspace)
+	if a.areas.filter != "" || len(a.areas.shown) != 7 {
+		t.Errorf("backspace left the filter %q", a.areas.filter)
+	}
+	typeKeys(a, 'p', 'a', 'l', term.KeyEscape)
+	if a.areas.searching || a.areas.filter != "" {
+		t.Errorf("Escape should drop the search, left %q", a.areas.filter)
+	}
+}
+
+func TestLateResultsAreDropped(t *testing.T) {
+	a := newTestApp(t, Options{})
+	var pending []func() func(*app)
+	a.load = func(fetch func() func(*app)) { pending = append(pending, fetch) }
+
+	a.exploreArea("pallet-town-area")
+	a.exploreArea("canalave-city-area")
+	// the second area answers first
+	pending[1]()(a)
+	pending[0]()(a)
+	if a.encounters.title != "canalave-city-area" {
+		t.Errorf("encounters are for %q, expected the area asked for last", a.encounters.title)
+	}
+	if a.loading != 0 {
+		t.Errorf("%d loads still counted as running", a.loading)
+	}
+}
+
+func TestView(t *testing.T) {
+	a := newTestApp(t, Options{Palette: ansi.Palette{Enabled: true}})
+	for _, size := range [][2]int{{80, 24}, {120, 40}, {40, 10}} {
+		a.width, a.height = size[0], size[1]
+		lines := a.view()
+		if len(lines) != a.height {
+			t.Errorf("%dx%d: view has %d lines", a.width, a.height, len(lines))
+		}
+		for i, line := range lines {
+			if w := ansi.Width(line); w != a.width {
+				t.Errorf("%dx%d: line %d is %d columns wide: %q", a.width, a.height, i, w, line)
+			}
+		}
+	}
+	a.width = 120
+	lines := a.view()
+	status := lines[len(lines)-1]
+	if !strings.Contains(status, "cache 0 hits, 1 misses") || !strings.Contains(status, "network idle, 1 requests") {
+		t.Errorf("status bar %q should count the one request made for the areas", status)
+	}
+}
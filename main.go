@@ -1,41 +1,279 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/lineedit"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/output"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/term"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/tui"
 )
 
 /* CONSTANTS */
 const CACHE_LIFE_IN_SECONDS = 60
+const CACHE_STALE_GRACE_IN_SECONDS = 600   // how long expired data can still be used when the API can't be reached
+const CACHE_COMPRESS_ABOVE_BYTES = 4 << 10 // responses bigger than this are stored gzipped
+const CACHE_MAX_BYTES = 64 << 20           // budget for the cached responses, after compression
+const PREFETCH_CONCURRENCY = 8             // requests in flight at once while prefetching
+const PREFETCH_PAGE_SIZE = 100             // location areas per page while prefetching
+const PREFETCH_TTL_IN_HOURS = 7 * 24       // how long prefetched data stays in the cache, e.g. through a flight
+const MAP_PAGE_SIZE = 20                   // location areas shown by each map and mapb
+const SHINY_ODDS = 512                     // one in this many caught Pokemon is shiny
+
+/* EXIT CODES for one-shot commands, e.g. "pokedex explore canalave-city-area" */
+const EXIT_OK = 0
+const EXIT_COMMAND_FAILED = 1 // the command ran and returned an error
+const EXIT_USAGE = 2          // there is no such command
+const EXIT_REQUESTED = -1     // the command was exit, so the caller should end the session; never a process exit code
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve-mock" {
+		if err := runServeMock(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	prefetch := flag.Bool("prefetch", false, "load location areas, the Pokemon in them and their details into the cache before starting")
+	prefetchAreas := flag.Int("prefetch-areas", 0, "with -prefetch, only load this many location areas (0 for all of them)")
+	offline := flag.Bool("offline", false, "use only the snapshot downloaded by the sync command, never the network")
+	snapshotDir := flag.String("snapshot-dir", defaultSnapshotDir(), "directory the sync command saves the offline snapshot in")
+	aliasFile := flag.String("alias-file", defaultAliasFile(), "file your own aliases are kept in (empty to not keep them)")
+	historyFile := flag.String("history-file", defaultHistoryFile(), "file the prompt's history is kept in between sessions (empty to not keep it)")
+	httpMode := flag.String("http-mode", os.Getenv("POKEDEX_HTTP_MODE"), "\"record\" saves API traffic to -http-fixtures, \"replay\" serves it from there instead of the network (default $POKEDEX_HTTP_MODE)")
+	httpFixtures := flag.String("http-fixtures", os.Getenv("POKEDEX_HTTP_FIXTURES"), "directory of recorded API traffic for -http-mode (default $POKEDEX_HTTP_FIXTURES)")
+	script := flag.String("script", "", "run the commands in this file instead of prompting for them")
+	noColor := flag.Bool("no-color", false, "never color the output, the same as -color never")
+	tuiMode := flag.Bool("tui", false, "browse areas, their Pokemon and details in a full-screen interface instead of the prompt")
+	configFile := flag.String("config", defaultConfigFile(), "JSON file the settings are read from, see \"config show\"")
+	// each setting has a flag, which wins over its environment variable and the config file
+	settingFlags := make(map[string]string)
+	defaults := defaultSettings()
+	for _, spec := range settingSpecs {
+		usage := fmt.Sprintf("%s (default %q, or $%s)", spec.description, spec.get(defaults), spec.env())
+		flag.Func(spec.flag(), usage, func(value string) error {
+			settingFlags[spec.key] = value
+			return nil
+		})
+	}
+	flag.Parse()
+	if *noColor {
+		settingFlags["color"] = string(ansi.ModeNever)
+	}
+	userSettings, sources, err := loadSettings(*configFile, os.LookupEnv, settingFlags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(EXIT_USAGE)
+	}
+
+	// the TUI shows the client's notes in its status bar, where they can't garble the screen
+	var notes *tui.Notes
+	var logger *log.Logger
+	if *tuiMode {
+		if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			fmt.Fprintln(os.Stderr, "-tui needs a terminal for both input and output")
+			os.Exit(EXIT_USAGE)
+		}
+		notes = &tui.Notes{}
+		logger = log.New(notes, "", 0)
+	}
+
 	// initalise repl environment
-	userConfig, scanner := ReplInitialisation()
+	userConfig, editor := ReplInitialisation(replOptions{
+		settings:     userSettings,
+		sources:      sources,
+		configFile:   *configFile,
+		offline:      *offline,
+		snapshotDir:  *snapshotDir,
+		httpMode:     *httpMode,
+		httpFixtures: *httpFixtures,
+		historyFile:  *historyFile,
+		aliasFile:    *aliasFile,
+		logger:       logger,
+	})
+
+	if *prefetch {
+		if err := runPrefetch(userConfig, *prefetchAreas); err != nil {
+			fmt.Fprintln(os.Stderr, fmt.Errorf("error prefetching: %w", err))
+		}
+	}
+
+	if *tuiMode {
+		code := runTUI(userConfig, notes)
+		shutdown(userConfig)
+		os.Exit(code)
+	}
+
+	// run a single command given on the command line instead of the repl
+	if flag.NArg() > 0 {
+		// the shell has already split the words, so they only need the command name lowercased
+		userPrompt := slices.Clone(flag.Args())
+		userPrompt[0] = strings.ToLower(userPrompt[0])
+		code := runCommand(userConfig, userPrompt)
+		if code == EXIT_REQUESTED {
+			code = EXIT_OK
+		}
+		shutdown(userConfig)
+		os.Exit(code)
+	}
+
+	// run commands from a script file, or from stdin when it is piped in
+	if *script != "" || !stdinIsTerminal() {
+		in := os.Stdin
+		if *script != "" {
+			f, err := os.Open(*script)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, fmt.Errorf("error opening script: %w", err))
+				shutdown(userConfig)
+				os.Exit(EXIT_USAGE)
+			}
+			defer f.Close()
+			in = f
+		}
+		code := runScript(userConfig, in)
+		shutdown(userConfig)
+		os.Exit(code)
+	}
 
 	// show help on start
-	GetCommands()["help"].callback(userConfig, nil)
+	GetCommands()["help"].callback(userConfig, commandInput{})
 
 	// cli user input loop
 	for isRunning := true; isRunning; {
-		fmt.Printf("\nPokedex > ")
-		if !scanner.Scan() {
-			fmt.Println("error parsing user input")
-			log.Fatal(1)
+		fmt.Println()
+		line, err := editor.ReadLine("Pokedex > ")
+		if errors.Is(err, lineedit.ErrInterrupted) {
+			// Ctrl-C throws away the line, like in a shell
+			continue
+		}
+		if err != nil {
+			// Ctrl-D ends the session like "exit" does
+			if err != io.EOF {
+				fmt.Fprintln(os.Stderr, fmt.Errorf("error reading user input: %w", err))
+			}
+			GetCommands()["exit"].callback(userConfig, commandInput{})
+			shutdown(userConfig)
+			os.Exit(EXIT_OK)
+		}
+		if err := editor.AddHistory(line); err != nil {
+			fmt.Fprintln(os.Stderr, err)
 		}
 
-		userPrompt := cleanInput(scanner.Text())
+		userPrompt, err := cleanInput(line)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
 		if len(userPrompt) == 0 {
 			continue
 		}
 
-		userCommand := userPrompt[0]
-		if userCommand, exists := GetCommands()[userCommand]; exists {
-			err := userCommand.callback(userConfig, userPrompt)
-			if err != nil {
-				fmt.Print(fmt.Errorf("error running command: %w", err))
+		if runCommand(userConfig, userPrompt) == EXIT_REQUESTED {
+			shutdown(userConfig)
+			os.Exit(EXIT_OK)
+		}
+	}
+}
+
+// runCommand runs a single command, given on the command line or read from a script, and returns
+// the exit code for it, or EXIT_REQUESTED for the exit command. Errors go to stderr so they
+// don't mix with the command's output.
+// A command can pick its own output format with "--output <format>", "--output=<format>" or "-o <format>".
+func runCommand(userConfig *config, userPrompt []string) int {
+	userPrompt, format, err := takeOutputFlag(userPrompt)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_USAGE
+	}
+	if format != "" {
+		sessionFormat := userConfig.Output
+		userConfig.Output = format
+		defer func() { userConfig.Output = sessionFormat }()
+	}
+
+	if _, isAlias := userConfig.Aliases[userPrompt[0]]; isAlias {
+		return runAlias(userConfig, userPrompt[0], userPrompt[1:])
+	}
+	command, exists := lookupCommand(userPrompt[0])
+	if !exists {
+		fmt.Fprintln(os.Stderr, unknownCommandMessage(userConfig, userPrompt[0]))
+		return EXIT_USAGE
+	}
+	if err := command.run(userConfig, userPrompt[1:]); err != nil {
+		if errors.Is(err, errExit) {
+			return EXIT_REQUESTED
+		}
+		if isUsageError(err) {
+			fmt.Fprintln(os.Stderr, err)
+			return EXIT_USAGE
+		}
+		fmt.Fprintln(os.Stderr, fmt.Errorf("error running command: %w", err))
+		return EXIT_COMMAND_FAILED
+	}
+	return EXIT_OK
+}
+
+// takeOutputFlag removes an output format flag from userPrompt, returning what is left and the
+// format it asked for, or "" if there was none.
+func takeOutputFlag(userPrompt []string) ([]string, output.Format, error) {
+	var rest []string
+	var format output.Format
+	for i := 0; i < len(userPrompt); i++ {
+		word := userPrompt[i]
+		name := ""
+		switch {
+		case word == "--output" || word == "-o":
+			if i+1 == len(userPrompt) {
+				return nil, "", fmt.Errorf("%s needs a format after it", word)
 			}
-		} else {
-			fmt.Println("Unknown command")
+			i++
+			name = userPrompt[i]
+		case strings.HasPrefix(word, "--output="):
+			name = strings.TrimPrefix(word, "--output=")
+		default:
+			rest = append(rest, word)
+			continue
+		}
+		f, err := output.ParseFormat(strings.ToLower(name))
+		if err != nil {
+			return nil, "", err
+		}
+		format = f
+	}
+	if len(rest) == 0 {
+		return nil, "", errors.New("no command given")
+	}
+	return rest, format, nil
+}
+
+// defaultSnapshotDir is where the offline snapshot lives unless -snapshot-dir says otherwise.
+func defaultSnapshotDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "pokedex-snapshot"
+	}
+	return filepath.Join(dir, "pokedex", "snapshot")
+}
+
+// defaultHistoryFile is where the prompt's history is kept unless -history-file says otherwise:
+// the pokedex directory in $XDG_STATE_HOME, or ~/.local/state if that isn't set.
+func defaultHistoryFile() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
 		}
+		dir = filepath.Join(home, ".local", "state")
 	}
+	return filepath.Join(dir, "pokedex", "history")
 }
@@ -1,107 +1,185 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"image"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/output"
 	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokestore"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/sprite"
 )
 
-func commandExit(userConfig *config, userPrompt []string) error {
-	userConfig.LocationCache.Stop()
-	fmt.Println("Closing the Pokedex... Goodbye!")
-	os.Exit(0)
-	return nil
+// errExit is returned by the exit command. Only the interactive loop ends the process for it;
+// a script just stops, keeping the exit code of the commands before it.
+var errExit = errors.New("exit")
+
+func commandExit(userConfig *config, input commandInput) error {
+	// stderr, so the goodbye doesn't mix with json or csv output
+	fmt.Fprintln(os.Stderr, "Closing the Pokedex... Goodbye!")
+	return errExit
 }
 
-func commandHelp(userConfig *config, userPrompt []string) error {
-	welcomeLine := "Welcome to the Pokedex!"
-	usageLine := "Usage:\n"
+// render writes the result of a command in the output format the user picked.
+func render(userConfig *config, result any) error {
+	return output.WriteColor(userConfig.Out, userConfig.Output, result, userConfig.Palette)
+}
 
-	fmt.Println(welcomeLine)
-	fmt.Println(usageLine)
-	for _, value := range GetCommands() {
-		fmt.Println(value.name+":", value.description)
+// shutdown stops the client and the cache before the program exits.
+func shutdown(userConfig *config) {
+	userConfig.PokeClient.Close()
+	if err := userConfig.LocationCache.Stop(); err != nil {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("problem stopping cache: %w", err))
 	}
-	return nil
 }
 
-func commandMap(userConfig *config, userPrompt []string) error {
-	locationSlice, nextURL, prevURL, err := pokeapi.GetLocationAreas(
-		userConfig.Next,
-		userConfig.LocationCache,
-	)
+func commandHelp(userConfig *config, input commandInput) error {
+	if name := input.Arg("command"); name != "" {
+		if expansion, ok := userConfig.Aliases[name]; ok {
+			return render(userConfig, newAliasList(map[string]string{name: expansion}, false))
+		}
+		command, ok := lookupCommand(name)
+		if !ok {
+			return errors.New(unknownCommandMessage(userConfig, name))
+		}
+		return render(userConfig, newCommandUsage(command))
+	}
+
+	var commands commandList
+	for _, command := range sortedCommands() {
+		commands.Commands = append(commands.Commands, commandSummary{Name: command.name, Usage: command.usage(), Description: command.description})
+	}
+	return render(userConfig, commands)
+}
+
+func commandMap(userConfig *config, input commandInput) error {
+	locationSlice, nextURL, prevURL, err := userConfig.PokeClient.GetLocationAreas(userConfig.Next)
 	if err != nil {
 		return fmt.Errorf("error: map command failed: %w", err)
 	}
 
 	userConfig.Previous = prevURL
 	userConfig.Next = nextURL
+	userConfig.rememberAreas(locationSlice)
 
-	for _, location := range locationSlice {
-		fmt.Println(location)
-	}
-	return nil
+	return render(userConfig, areaPage{Areas: locationSlice, Next: nextURL, Previous: prevURL})
 }
 
-func commandMapBack(userConfig *config, userPrompt []string) error {
+func commandMapBack(userConfig *config, input commandInput) error {
 	// check to see if user is at the beginning of the exploration map.
 	if userConfig.Previous == "" {
-		fmt.Println("you're on the first page")
-		return nil
+		return render(userConfig, message{"you're on the first page"})
 	}
 
-	locationSlice, nextURL, prevURL, err := pokeapi.GetLocationAreas(
-		userConfig.Previous,
-		userConfig.LocationCache,
-	)
+	locationSlice, nextURL, prevURL, err := userConfig.PokeClient.GetLocationAreas(userConfig.Previous)
 	if err != nil {
 		return fmt.Errorf("error: mapb command failed: %w", err)
 	}
 
 	userConfig.Next = nextURL
 	userConfig.Previous = prevURL
+	userConfig.rememberAreas(locationSlice)
 
-	for _, location := range locationSlice {
-		fmt.Println(location)
-	}
-	return nil
+	return render(userConfig, areaPage{Areas: locationSlice, Next: nextURL, Previous: prevURL})
 }
 
-func commandExplore(userConfig *config, userPrompt []string) error {
-	if len(userPrompt) < 2 {
-		return errors.New("you must provide an area to explore after the \"explore\" command.\nE.g. \"explore <area name>\"")
-	}
-	userProvidedAreaName := userPrompt[1]
+func commandExplore(userConfig *config, input commandInput) error {
+	userProvidedAreaName := input.Arg("area name")
 
-	pokemonInAreaSlice, err := pokeapi.GetPokemonInArea(userProvidedAreaName, userConfig.LocationCache)
+	pokemonInAreaSlice, err := userConfig.PokeClient.GetPokemonInArea(userProvidedAreaName)
+	if isUnknownName(err, userProvidedAreaName, userConfig.PokeClient.GetLocationAreaNames) {
+		return unknownAreaError(userConfig, userProvidedAreaName)
+	}
 	if err != nil {
-		return errors.New("error: problem getting Pokemon in area")
+		return fmt.Errorf("error: problem getting Pokemon in area: %w", err)
 	}
 
+	gameVersion := input.String("game-version")
+	if gameVersion == "" {
+		gameVersion = userConfig.Settings.GameVersion
+	}
+	encounters := encounterList{Area: userProvidedAreaName, GameVersion: gameVersion, Pokemon: []string{}}
 	for _, pokemon := range pokemonInAreaSlice {
-		fmt.Printf(" - %s\n", pokemon.Pokemon.Name)
+		if gameVersion != "" && !slices.ContainsFunc(pokemon.VersionDetails, func(v pokeapi.EncounterVersion) bool { return v.Version.Name == gameVersion }) {
+			continue
+		}
+		encounters.Pokemon = append(encounters.Pokemon, pokemon.Pokemon.Name)
 	}
-	return nil
+	userConfig.LastEncounters = encounters.Pokemon
+	return render(userConfig, encounters)
 }
 
-func commandCatch(userConfig *config, userPrompt []string) error {
-	if len(userPrompt) < 2 {
-		return errors.New("you must provide an pokemon name after the \"catch\" command")
+func commandCatch(userConfig *config, input commandInput) error {
+	userProvidedPokemonName := input.Arg("pokemon name")
+
+	if input.Bool("all") {
+		if userProvidedPokemonName != "" {
+			return &usageError{command: GetCommands()["catch"], problem: "give a Pokemon name or --all, not both"}
+		}
+		return catchAll(userConfig)
+	}
+	if userProvidedPokemonName == "" {
+		return &usageError{command: GetCommands()["catch"], problem: "missing <pokemon name>"}
 	}
-	userProvidedPokemonName := userPrompt[1]
 
 	if _, ok := userConfig.Pokedex[userProvidedPokemonName]; ok {
 		return fmt.Errorf("you already have %s in your Pokedex", userProvidedPokemonName)
 	}
+	attempt, err := throwPokeball(userConfig, userProvidedPokemonName)
+	if err != nil {
+		return err
+	}
+	return render(userConfig, attempt)
+}
 
-	fmt.Printf("Throwing a Pokeball at %s...\n", userProvidedPokemonName)
+// catchAll tries to catch every Pokemon found by the last explore that isn't in the Pokedex yet.
+func catchAll(userConfig *config) error {
+	if len(userConfig.LastEncounters) == 0 {
+		return errors.New("there is nothing to catch, \"catch --all\" tries to catch the Pokemon found by the last \"explore\"")
+	}
+	attempts := catchAttempts{Attempts: []catchAttempt{}}
+	failed := 0
+	for _, name := range userConfig.LastEncounters {
+		if _, ok := userConfig.Pokedex[name]; ok {
+			continue
+		}
+		// one Pokemon that can't be fetched doesn't stop the others being caught
+		attempt, err := throwPokeball(userConfig, name)
+		if err != nil {
+			attempt = catchAttempt{Pokemon: name, Error: err.Error()}
+			failed++
+		}
+		attempts.Attempts = append(attempts.Attempts, attempt)
+	}
+	if len(attempts.Attempts) == 0 {
+		return render(userConfig, message{"You already have every Pokemon from the last explore in your Pokedex."})
+	}
+	if err := render(userConfig, attempts); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("error: could not throw a Pokeball at %d of %d Pokemon", failed, len(attempts.Attempts))
+	}
+	return nil
+}
 
-	PokemonDetails, err := pokeapi.GetPokemonDetails(userProvidedPokemonName, userConfig.LocationCache)
+// throwPokeball makes one attempt at catching the Pokemon called name, adding it to the Pokedex
+// if it is caught.
+func throwPokeball(userConfig *config, name string) (catchAttempt, error) {
+	PokemonDetails, err := userConfig.PokeClient.GetPokemonDetails(name)
+	if isUnknownName(err, name, userConfig.PokeClient.GetPokemonSpeciesNames) {
+		return catchAttempt{}, unknownPokemonError(userConfig, name)
+	}
 	if err != nil {
-		return errors.New("error: problem getting Pokemon details")
+		return catchAttempt{}, fmt.Errorf("error: problem getting Pokemon details: %w", err)
 	}
 
 	// The base experience gained for defeating this Pokémon (int).
@@ -109,53 +187,276 @@ func commandCatch(userConfig *config, userPrompt []string) error {
 
 	// logic for determining if catch attempt is successful
 	baseExpCapped := min(pokemonBaseExperience, 400)
-	fmt.Printf("Base Experience: %d\n", baseExpCapped)
+	randChance := 30 * (userConfig.Rand.Intn(9) + 1)
 
-	randChance := 30 * (rand.Intn(9) + 1)
-	fmt.Printf("randChance: %d\n", randChance)
+	attempt := catchAttempt{
+		Pokemon:        name,
+		BaseExperience: baseExpCapped,
+		Roll:           randChance,
+		Caught:         randChance > baseExpCapped,
+	}
+	if attempt.Caught {
+		attempt.Shiny = userConfig.Rand.Intn(SHINY_ODDS) == 0
+		userConfig.Pokedex[name] = caughtPokemon{Pokemon: PokemonDetails, Shiny: attempt.Shiny}
+		if userConfig.Settings.SavePath != "" {
+			if err := savePokedex(userConfig.Settings.SavePath, userConfig.Pokedex); err != nil {
+				fmt.Fprintln(os.Stderr, fmt.Errorf("problem saving your Pokedex, %s will be lost when you exit: %w", name, err))
+			}
+		}
+	}
+	return attempt, nil
+}
+
+func commandInspect(userConfig *config, input commandInput) error {
+	userProvidedPokemonName := input.Arg("pokemon name")
 
-	if randChance > baseExpCapped {
-		fmt.Println(":) Pokemon caught!")
-		userConfig.Pokedex[userProvidedPokemonName] = PokemonDetails
-	} else {
-		fmt.Println(";( Pokemon got away!")
+	p, ok := userConfig.Pokedex[userProvidedPokemonName]
+	if !ok {
+		var caught []string
+		for name := range userConfig.Pokedex {
+			caught = append(caught, name)
+		}
+		if s := suggestions(userProvidedPokemonName, caught); s != "" {
+			return fmt.Errorf("%s is not in your Pokedex. Did you mean %s?", userProvidedPokemonName, s)
+		}
+		return fmt.Errorf("%s is not in your Pokedex. You must catch a Pokemon before you can inspect it", userProvidedPokemonName)
+	}
+	details := newPokemonDetails(p)
+	if input.Bool("sprite") {
+		img, err := loadSprite(userConfig, p.SpriteURL(p.Shiny, false))
+		if err != nil {
+			// the details are still worth showing without a picture
+			fmt.Fprintln(os.Stderr, fmt.Errorf("problem showing the sprite: %w", err))
+		}
+		details.sprite = img
 	}
+	return render(userConfig, details)
+}
 
-	return nil
+func commandSprite(userConfig *config, input commandInput) error {
+	name := input.Arg("pokemon name")
+	shiny, back := input.Bool("shiny"), input.Bool("back")
+
+	p, err := userConfig.PokeClient.GetPokemonDetails(name)
+	if isUnknownName(err, name, userConfig.PokeClient.GetPokemonSpeciesNames) {
+		return unknownPokemonError(userConfig, name)
+	}
+	if err != nil {
+		return fmt.Errorf("error: problem getting Pokemon details: %w", err)
+	}
+	url := p.SpriteURL(shiny, back)
+	img, err := loadSprite(userConfig, url)
+	if err != nil {
+		return err
+	}
+	return render(userConfig, spriteImage{Pokemon: name, URL: url, Shiny: shiny, Back: back, img: img})
 }
 
-func commandInspect(userConfig *config, userPrompt []string) error {
-	if len(userPrompt) < 2 {
-		return errors.New("you must provide an pokemon name after the \"inspect\" command")
+// loadSprite downloads the sprite at url, through the cache, and decodes it.
+func loadSprite(userConfig *config, url string) (image.Image, error) {
+	if url == "" {
+		return nil, errors.New("PokeAPI has no sprite for that view of this Pokemon")
+	}
+	data, err := userConfig.PokeClient.GetSprite(url)
+	if err != nil {
+		return nil, err
 	}
-	userProvidedPokemonName := userPrompt[1]
+	return sprite.Decode(data)
+}
 
-	p, ok := userConfig.Pokedex[userProvidedPokemonName]
-	if !ok {
-		fmt.Printf("%s is not in your Pokedex. You must catch a Pokemon before you can inspect it.\n", userProvidedPokemonName)
-		return nil
+func commandPokedex(userConfig *config, input commandInput) error {
+	caught := pokedexList{Pokemon: []string{}, Shiny: []string{}}
+	for _, p := range userConfig.Pokedex {
+		caught.Pokemon = append(caught.Pokemon, p.Name)
+		if p.Shiny {
+			caught.Shiny = append(caught.Shiny, p.Name)
+		}
 	}
-	fmt.Println("Name:", p.Name)
-	fmt.Println("Height:", p.Height)
-	fmt.Println("Weight:", p.Weight)
-	fmt.Println("Stats:")
-	for _, s := range p.Stats {
-		fmt.Printf("-%s: %d\n", s.Stat.Name, s.BaseStat)
+	sort.Strings(caught.Pokemon)
+	sort.Strings(caught.Shiny)
+	return render(userConfig, caught)
+}
+
+func commandCache(userConfig *config, input commandInput) error {
+	key := input.Arg("key")
+	if key != "" && input.Arg("subcommand") != "evict" {
+		return fmt.Errorf("only \"cache evict\" takes a key")
 	}
-	fmt.Println("Types:")
-	for _, t := range p.Types {
-		fmt.Println("-", t.Type.Name)
+
+	switch input.Arg("subcommand") {
+	case "stats":
+		return render(userConfig, newCacheStats(userConfig.LocationCache.Stats()))
+	case "list":
+		entries := userConfig.LocationCache.List()
+		if input.Bool("stale") {
+			entries = slices.DeleteFunc(entries, func(e pokecache.EntryInfo[string]) bool { return !e.Stale })
+		}
+		return render(userConfig, newCacheEntries(entries))
+	case "clear":
+		removed := userConfig.PokeClient.Clear()
+		return render(userConfig, message{fmt.Sprintf("Removed %d entries from the cache.", removed)})
+	case "evict":
+		if key == "" {
+			return errors.New("you must provide a key after \"cache evict\". Use \"cache list\" to see keys")
+		}
+		if !userConfig.PokeClient.Evict(key) {
+			return fmt.Errorf("no cache entry for %s", key)
+		}
+		return render(userConfig, message{"Evicted " + key})
+	default: // parse only lets through the declared subcommands
+		return fmt.Errorf("unknown cache subcommand %q", input.Arg("subcommand"))
 	}
-	return nil
 }
 
-func commandPokedex(userConfig *config, userPrompt []string) error {
-	if len(userConfig.Pokedex) == 0 {
-		fmt.Println("Pokedex is empty. You haven't caught any Pokemon yet.")
+func commandAlias(userConfig *config, input commandInput) error {
+	name, expansion := input.Arg("name"), input.Arg("commands")
+	switch {
+	case name == "":
+		return render(userConfig, newAliasList(userConfig.Aliases, true))
+	case expansion == "":
+		if expansion, ok := userConfig.Aliases[name]; ok {
+			return render(userConfig, newAliasList(map[string]string{name: expansion}, false))
+		}
+		if command, ok := lookupCommand(name); ok && command.name != name {
+			return render(userConfig, aliasList{Aliases: []aliasEntry{{Name: name, Commands: command.name, BuiltIn: true}}})
+		}
+		return fmt.Errorf("there is no alias called %s", name)
+	}
+
+	if err := checkNewAlias(name, expansion, userConfig.Aliases); err != nil {
+		return err
+	}
+	userConfig.Aliases[name] = expansion
+	if err := saveUserAliases(userConfig); err != nil {
+		return err
+	}
+	return render(userConfig, message{fmt.Sprintf("%s now stands for: %s", name, expansion)})
+}
+
+func commandUnalias(userConfig *config, input commandInput) error {
+	name := input.Arg("name")
+	if _, ok := userConfig.Aliases[name]; !ok {
+		if command, ok := lookupCommand(name); ok && command.name != name {
+			return fmt.Errorf("%s is a built-in alias for %s and can't be removed", name, command.name)
+		}
+		return fmt.Errorf("there is no alias called %s", name)
+	}
+	delete(userConfig.Aliases, name)
+	if err := saveUserAliases(userConfig); err != nil {
+		return err
+	}
+	return render(userConfig, message{"Removed the alias " + name})
+}
+
+// saveUserAliases writes the user's aliases to their alias file, if they have one.
+func saveUserAliases(userConfig *config) error {
+	if userConfig.AliasFile == "" {
 		return nil
 	}
-	for _, p := range userConfig.Pokedex {
-		fmt.Println(" -", p.Name)
+	if err := saveAliases(userConfig.AliasFile, userConfig.Aliases); err != nil {
+		return fmt.Errorf("error: problem saving aliases: %w", err)
 	}
 	return nil
 }
+
+func commandConfig(userConfig *config, input commandInput) error {
+	// "show" is the only subcommand for now, parse has checked that's what was asked for
+	shown := settingsList{File: userConfig.ConfigFile, Settings: []settingEntry{}}
+	for _, spec := range settingSpecs {
+		source := userConfig.SettingSources[spec.key]
+		if source == "" {
+			source = sourceDefault
+		}
+		shown.Settings = append(shown.Settings, settingEntry{Key: spec.key, Value: spec.get(userConfig.Settings), Source: source})
+	}
+	return render(userConfig, shown)
+}
+
+func commandPrefetch(userConfig *config, input commandInput) error {
+	maxAreas, err := parseMaxAreas(input)
+	if err != nil {
+		return err
+	}
+	return runPrefetch(userConfig, maxAreas)
+}
+
+// parseMaxAreas reads the optional number of areas given to prefetch and sync. 0 means all of them.
+func parseMaxAreas(input commandInput) (int, error) {
+	areas := input.Arg(areasArg.name)
+	if areas == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(areas)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("the number of areas must be a positive number, not %q", areas)
+	}
+	return n, nil
+}
+
+// runPrefetch loads up to maxAreas location areas (0 for all), the Pokemon found in them and
+// their details into the cache, showing progress as it goes and listing anything that failed.
+func runPrefetch(userConfig *config, maxAreas int) error {
+	fmt.Fprintln(os.Stderr, "Prefetching location areas and Pokemon...")
+	failures, err := userConfig.PokeClient.Prefetch(context.Background(), pokeapi.PrefetchOptions{
+		StartURL:    userConfig.PokeClient.LocationAreasURL(PREFETCH_PAGE_SIZE),
+		MaxAreas:    maxAreas,
+		Concurrency: PREFETCH_CONCURRENCY,
+		TTL:         PREFETCH_TTL_IN_HOURS * time.Hour,
+	}, func(p pokeapi.PrefetchProgress) {
+		printPrefetchProgress(p)
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return fmt.Errorf("prefetch stopped: %w", err)
+	}
+
+	printPrefetchFailures(failures)
+	if len(failures) > 0 {
+		return fmt.Errorf("prefetch finished with %d failures", len(failures))
+	}
+	return render(userConfig, message{"Prefetch finished."})
+}
+
+func commandSync(userConfig *config, input commandInput) error {
+	maxAreas, err := parseMaxAreas(input)
+	if err != nil {
+		return err
+	}
+	if userConfig.PokeClient.Offline() {
+		return errors.New("can't sync in offline mode, restart the Pokedex without \"-offline\" first")
+	}
+	snapshot, err := pokestore.Open(userConfig.SnapshotDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Syncing location areas and Pokemon to", snapshot.Dir())
+	failures, err := userConfig.PokeClient.Sync(context.Background(), snapshot, pokeapi.PrefetchOptions{
+		// the same page size as map, so map and mapb find their pages in the snapshot
+		StartURL:    userConfig.PokeClient.LocationAreasURL(MAP_PAGE_SIZE),
+		MaxAreas:    maxAreas,
+		Concurrency: PREFETCH_CONCURRENCY,
+	}, printPrefetchProgress)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return fmt.Errorf("sync stopped: %w", err)
+	}
+
+	printPrefetchFailures(failures)
+	if len(failures) > 0 {
+		return fmt.Errorf("%d resources could not be synced, run \"sync\" again to retry", len(failures))
+	}
+	return render(userConfig, message{"Sync finished. Start the Pokedex with \"-offline\" to use the snapshot."})
+}
+
+// printPrefetchProgress shows the progress of a prefetch or sync, overwriting the previous progress line.
+func printPrefetchProgress(p pokeapi.PrefetchProgress) {
+	fmt.Fprintf(os.Stderr, "\rareas %d/%d, Pokemon %d/%d, failures %d", p.AreasDone, p.Areas, p.PokemonDone, p.Pokemon, p.Failures)
+}
+
+// printPrefetchFailures lists the resources a prefetch or sync could not load.
+func printPrefetchFailures(failures []pokeapi.PrefetchFailure) {
+	for _, f := range failures {
+		fmt.Fprintf(os.Stderr, " - could not load %s: %v\n", f.Resource, f.Err)
+	}
+}
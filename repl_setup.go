@@ -1,38 +1,216 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math/rand"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/lineedit"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/output"
 	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
 	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokestore"
 )
 
-// cleanInput splits the user's input into "words" based on whitespace. It should also lowercase the input and trim any leading or trailing whitespace.
-func cleanInput(text string) []string {
-	textTrimmedToLower := strings.ToLower(strings.TrimSpace(text))
-	return strings.Fields(textTrimmedToLower)
+// cleanInput splits the user's input into "words" the way a shell would, and lowercases the
+// command name. Arguments keep their case, because only some are PokeAPI identifiers: those are
+// lowercased when the command's arguments are parsed.
+//
+// Words are separated by whitespace. Single quotes keep everything inside them as it is, double
+// quotes do the same except that \" and \\ stand for " and \, and outside quotes a backslash
+// keeps the next character as it is. Quoted and unquoted parts next to each other make up one
+// word, so --name="Ash Ketchum" is the word --name=Ash Ketchum.
+func cleanInput(text string) ([]string, error) {
+	words, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) > 0 {
+		words[0] = strings.ToLower(words[0])
+	}
+	return words, nil
+}
+
+// tokenize does the splitting for cleanInput.
+func tokenize(text string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false // a word has started, even if it is still empty like ""
+	var quote rune  // the quote the tokenizer is inside, or 0
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+				i++
+				word.WriteRune(runes[i])
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, errors.New("input ends with a lone backslash")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("missing closing %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// replOptions are the command line settings that shape the repl environment.
+// Zero settings fall back to the defaults, except that an empty SavePath keeps nothing.
+type replOptions struct {
+	settings     settings          // the user's preferences, see settings.go
+	sources      map[string]string // where each setting came from, for "config show"
+	configFile   string            // where the settings were read from
+	offline      bool              // serve everything from the snapshot instead of the network
+	snapshotDir  string            // where the offline snapshot is kept
+	httpMode     string            // "record" or "replay" API traffic, or "" to just use the network
+	httpFixtures string            // where recorded API traffic is kept
+	historyFile  string            // where the prompt's history is kept between sessions, or "" to not keep it
+	aliasFile    string            // where user aliases are kept, or "" to not keep them
+	logger       *log.Logger       // where the client's notes about stale data go, or nil for stderr
 }
 
 // initialise the repl environment for main.go
-// returns an instance of config for the user and a scanner to read input
+// returns an instance of config for the user and a line editor to read input
 // also creates a cache to be used to minimise network calls
-func ReplInitialisation() (*config, *bufio.Scanner) {
-	locationCache, err := pokecache.NewCache(CACHE_LIFE_IN_SECONDS * time.Second)
+func ReplInitialisation(opts replOptions) (*config, *lineedit.Editor) {
+	defaults := defaultSettings()
+	if opts.settings.APIURL == "" {
+		opts.settings.APIURL = defaults.APIURL
+	}
+	if opts.settings.CacheTTL == 0 {
+		opts.settings.CacheTTL = defaults.CacheTTL
+	}
+	if opts.settings.CacheSizeMB == 0 {
+		opts.settings.CacheSizeMB = defaults.CacheSizeMB
+	}
+	if opts.settings.Output == "" {
+		opts.settings.Output = defaults.Output
+	}
+	if opts.settings.Color == "" {
+		opts.settings.Color = defaults.Color
+	}
+	seed := opts.settings.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	locationCache, err := pokecache.NewByteCache(
+		opts.settings.CacheTTL,
+		pokecache.WithStaleGrace(CACHE_STALE_GRACE_IN_SECONDS*time.Second),
+		pokecache.WithCompression(CACHE_COMPRESS_ABOVE_BYTES),
+		pokecache.WithMaxBytes(opts.settings.CacheSizeMB<<20),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("problem initialising cache in userConfig: %w", err))
+	}
+	clientOpts := []pokeapi.ClientOption{pokeapi.WithBaseURL(opts.settings.APIURL)}
+	if opts.logger != nil {
+		clientOpts = append(clientOpts, pokeapi.WithLogger(opts.logger))
+	}
+	if opts.httpMode != "" {
+		recorder, err := pokeapi.NewRecorder(opts.httpFixtures, pokeapi.RecordMode(opts.httpMode), nil)
+		if err != nil {
+			log.Fatal(fmt.Errorf("problem setting up -http-mode: %w", err))
+		}
+		clientOpts = append(clientOpts, pokeapi.WithTransport(recorder))
+	}
+	if opts.offline {
+		snapshot, err := pokestore.Open(opts.snapshotDir)
+		if err != nil {
+			log.Fatal(fmt.Errorf("problem opening offline snapshot: %w", err))
+		}
+		if syncedAt, ok := snapshot.SyncedAt(); ok {
+			fmt.Fprintf(os.Stderr, "Offline mode: using the snapshot synced at %s.\n", syncedAt.Local().Format(time.DateTime))
+		} else {
+			fmt.Fprintln(os.Stderr, "Offline mode: there is no complete snapshot yet. Run \"sync\" while online first.")
+		}
+		clientOpts = append(clientOpts, pokeapi.WithOffline(snapshot))
+	}
+	pokeClient, err := pokeapi.NewClient(locationCache, clientOpts...)
 	if err != nil {
-		fmt.Print(fmt.Errorf("problem initialising cache in userConfig: %w", err))
+		fmt.Fprintln(os.Stderr, fmt.Errorf("problem initialising PokeAPI client in userConfig: %w", err))
+	}
+	pokedex := make(map[string]caughtPokemon)
+	if opts.settings.SavePath != "" {
+		if pokedex, err = loadPokedex(opts.settings.SavePath); err != nil {
+			log.Fatal(fmt.Errorf("problem loading your caught Pokemon: %w", err))
+		}
+	}
+	aliases := make(map[string]string)
+	if opts.aliasFile != "" {
+		if aliases, err = loadAliases(opts.aliasFile); err != nil {
+			fmt.Fprintln(os.Stderr, fmt.Errorf("problem loading aliases: %w", err))
+			aliases = make(map[string]string)
+		}
 	}
 	var userConfig = &config{
-		Next:          "https://pokeapi.co/api/v2/location-area/?limit=20&offset=0",
-		Previous:      "",
-		LocationCache: locationCache,
-		Pokedex:       make(map[string]pokeapi.Pokemon),
+		Next:           pokeClient.LocationAreasURL(MAP_PAGE_SIZE),
+		Previous:       "",
+		LocationCache:  locationCache,
+		PokeClient:     pokeClient,
+		SnapshotDir:    opts.snapshotDir,
+		Output:         opts.settings.Output,
+		Out:            os.Stdout,
+		Palette:        ansi.NewPalette(ansi.Enabled(opts.settings.Color, os.Stdout, os.Getenv), os.Getenv),
+		Pokedex:        pokedex,
+		Aliases:        aliases,
+		AliasFile:      opts.aliasFile,
+		Settings:       opts.settings,
+		SettingSources: opts.sources,
+		ConfigFile:     opts.configFile,
+		Rand:           rand.New(rand.NewSource(seed)),
 	}
-	scanner := bufio.NewScanner(os.Stdin)
-	return userConfig, scanner
+	editor := lineedit.New(os.Stdin, os.Stdout)
+	editor.Complete = userConfig.complete
+	if opts.historyFile != "" {
+		if err := editor.LoadHistory(opts.historyFile); err != nil {
+			fmt.Fprintln(os.Stderr, fmt.Errorf("problem loading history: %w", err))
+		}
+	}
+	return userConfig, editor
 }
 
 // config represents the user's state when exploring the Pokemon universe.
@@ -41,29 +219,116 @@ func ReplInitialisation() (*config, *bufio.Scanner) {
 type config struct {
 	Next          string
 	Previous      string
-	LocationCache *pokecache.Cache
-	Pokedex       map[string]pokeapi.Pokemon // violating clean architecture
+	LocationCache *pokecache.ByteCache
+	PokeClient    *pokeapi.Client
+	SnapshotDir   string                   // where the sync command saves the offline snapshot
+	Output        output.Format            // how command results are rendered
+	Out           io.Writer                // where command results are written
+	Palette       ansi.Palette             // colors for results written to Out, disabled when Out isn't a terminal
+	Pokedex       map[string]caughtPokemon // violating clean architecture
+	Aliases       map[string]string        // user aliases, from the name to the commands it stands for
+	AliasFile     string                   // where the alias and unalias commands save Aliases, or "" to not save them
+
+	Settings       settings          // the preferences the session started with
+	SettingSources map[string]string // where each setting came from
+	ConfigFile     string            // where the settings were read from
+	Rand           *rand.Rand        // rolls the dice for catch, seeded by Settings.Seed
+
+	SeenAreas      []string // area names from the map pages shown so far, offered by tab completion
+	LastEncounters []string // the Pokemon found by the last explore
+}
+
+// rememberAreas adds the areas on a map page to SeenAreas.
+func (c *config) rememberAreas(areas []string) {
+	for _, area := range areas {
+		if !slices.Contains(c.SeenAreas, area) {
+			c.SeenAreas = append(c.SeenAreas, area)
+		}
+	}
 }
 
 // cliCommand represents a command that can be called by the user from the CLI.
 type cliCommand struct {
 	name        string
 	description string
-	// callback takes userConfig and user prompt, split into words
-	callback func(*config, []string) error
+	aliases     []string   // other names the command can be called by
+	args        []argSpec  // positional arguments, in order
+	flags       []flagSpec // flags, which can go anywhere after the command name
+	examples    []string
+	// callback takes userConfig and the user's arguments, already checked against args and flags
+	callback func(*config, commandInput) error
+}
+
+// run checks words, the user's arguments after the command name, and calls the command.
+func (c cliCommand) run(userConfig *config, words []string) error {
+	input, err := c.parse(words)
+	if err != nil {
+		return err
+	}
+	return c.callback(userConfig, input)
+}
+
+// lookupCommand finds a command by its name or one of its aliases.
+func lookupCommand(name string) (cliCommand, bool) {
+	commands := GetCommands()
+	if command, ok := commands[name]; ok {
+		return command, true
+	}
+	for _, command := range commands {
+		if slices.Contains(command.aliases, name) {
+			return command, true
+		}
+	}
+	return cliCommand{}, false
+}
+
+// commandNames returns the name and aliases of every command, for completion and suggestions.
+func commandNames() []string {
+	var names []string
+	for name, command := range GetCommands() {
+		names = append(names, name)
+		names = append(names, command.aliases...)
+	}
+	return names
 }
 
+// commandNames returns commandNames with the user's aliases added.
+func (c *config) commandNames() []string {
+	names := commandNames()
+	for name := range c.Aliases {
+		names = append(names, name)
+	}
+	return names
+}
+
+// sortedCommands returns every command in alphabetical order.
+func sortedCommands() []cliCommand {
+	var commands []cliCommand
+	for _, command := range GetCommands() {
+		commands = append(commands, command)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].name < commands[j].name })
+	return commands
+}
+
+// areasArg is the optional number of areas taken by prefetch and sync.
+var areasArg = argSpec{name: "number of areas", description: "only load this many location areas, instead of all of them", optional: true}
+
 // GetCommands returns the hardcoded map of available commands
 func GetCommands() map[string]cliCommand {
 	return map[string]cliCommand{
 		"help": {
 			name:        "help",
-			description: "Display a help message.",
+			aliases:     []string{"h"},
+			description: "Display a help message, or detailed help for one command.",
+			args:        []argSpec{{name: "command", description: "the command to explain", optional: true, identifier: true}},
+			examples:    []string{"help", "help explore"},
 			callback:    commandHelp,
 		},
 		"exit": {
 			name:        "exit",
 			description: "Exit the Pokedex.",
+			aliases:     []string{"quit"},
 			callback:    commandExit,
 		},
 		"map": {
@@ -78,23 +343,104 @@ func GetCommands() map[string]cliCommand {
 		},
 		"explore": {
 			name:        "explore",
-			description: "Explore an area for Pokemon. e.g. \"explore <area name>\". Find area names by using \"map\" first.",
-			callback:    commandExplore,
+			aliases:     []string{"e"},
+			description: "Explore an area for Pokemon. Find area names by using \"map\" first.",
+			args:        []argSpec{{name: "area name", description: "a location area, as listed by \"map\"", identifier: true}},
+			flags: []flagSpec{
+				{name: "game-version", kind: flagString, description: "only show Pokemon found in this game, instead of the game_version setting"},
+			},
+			examples: []string{"explore canalave-city-area", "explore canalave-city-area --game-version diamond"},
+			callback: commandExplore,
 		},
 		"catch": {
 			name:        "catch",
-			description: "Try to catch a Pokemon. e.g. \"catch <pokemon name>\". Use \"explore\" command to find Pokemon names.",
-			callback:    commandCatch,
+			description: "Try to catch a Pokemon. Use \"explore\" command to find Pokemon names.",
+			aliases:     []string{"c"},
+			args:        []argSpec{{name: "pokemon name", description: "the Pokemon to throw a Pokeball at", optional: true, identifier: true}},
+			flags: []flagSpec{
+				{name: "all", short: "a", kind: flagBool, description: "throw a Pokeball at every Pokemon from the last explore that isn't in your Pokedex yet"},
+			},
+			examples: []string{"catch pikachu", "catch --all"},
+			callback: commandCatch,
 		},
 		"inspect": {
 			name:        "inspect",
-			description: "See details about a Pokemon. e.g. \"inspect <pokemon name>\". You must catch a Pokemon before you can inspect it.",
-			callback:    commandInspect,
+			aliases:     []string{"i"},
+			description: "See details about a Pokemon. You must catch a Pokemon before you can inspect it.",
+			args:        []argSpec{{name: "pokemon name", description: "a Pokemon in your Pokedex", identifier: true}},
+			flags: []flagSpec{
+				{name: "sprite", short: "s", kind: flagBool, description: "draw the Pokemon's sprite beside its details"},
+			},
+			examples: []string{"inspect pikachu", "inspect pikachu --sprite"},
+			callback: commandInspect,
+		},
+		"sprite": {
+			name:        "sprite",
+			description: "Draw a Pokemon's sprite in the terminal. Any Pokemon works, caught or not.",
+			args:        []argSpec{{name: "pokemon name", description: "the Pokemon to draw", identifier: true}},
+			flags: []flagSpec{
+				{name: "shiny", kind: flagBool, description: "draw it in its shiny colors"},
+				{name: "back", kind: flagBool, description: "draw it from behind"},
+			},
+			examples: []string{"sprite pikachu", "sprite pikachu --shiny --back"},
+			callback: commandSprite,
 		},
 		"pokedex": {
 			name:        "pokedex",
+			aliases:     []string{"p"},
 			description: "View your Pokedex. A list of all caught Pokemon.",
 			callback:    commandPokedex,
 		},
+		"cache": {
+			name:        "cache",
+			description: "Inspect or manage the local cache.",
+			args: []argSpec{
+				{name: "subcommand", description: "what to do: show stats, list entries, clear everything or evict one key", choices: []string{"stats", "list", "clear", "evict"}, identifier: true},
+				{name: "key", description: "with evict, the key to remove, as shown by \"cache list\"", optional: true},
+			},
+			flags: []flagSpec{
+				{name: "stale", kind: flagBool, description: "with list, only show entries that have expired"},
+			},
+			examples: []string{"cache stats", "cache list --stale", "cache evict https://pokeapi.co/api/v2/pokemon/pikachu"},
+			callback: commandCache,
+		},
+		"alias": {
+			name:        "alias",
+			description: "List aliases, or define your own alias for one or more commands. Separate commands with \";\", and use $1 to $9 for the alias's arguments.",
+			args: []argSpec{
+				{name: "name", description: "the alias to show or define", optional: true, identifier: true},
+				{name: "commands", description: "what the alias stands for, in quotes", optional: true},
+			},
+			examples: []string{"alias", "alias ex explore", "alias scout 'explore $1; catch --all'"},
+			callback: commandAlias,
+		},
+		"unalias": {
+			name:        "unalias",
+			description: "Remove one of your aliases.",
+			args:        []argSpec{{name: "name", description: "the alias to remove", identifier: true}},
+			examples:    []string{"unalias scout"},
+			callback:    commandUnalias,
+		},
+		"config": {
+			name:        "config",
+			description: "Show the settings and where each one came from: a flag, an environment variable, the config file or the default.",
+			args:        []argSpec{{name: "subcommand", description: "what to do: show the settings", choices: []string{"show"}, identifier: true}},
+			examples:    []string{"config show", "config show -o json"},
+			callback:    commandConfig,
+		},
+		"prefetch": {
+			name:        "prefetch",
+			description: "Load location areas, the Pokemon in them and their details into the cache ahead of time.",
+			args:        []argSpec{areasArg},
+			examples:    []string{"prefetch", "prefetch 10"},
+			callback:    commandPrefetch,
+		},
+		"sync": {
+			name:        "sync",
+			description: "Download location areas, the Pokemon in them and their details for offline use with \"-offline\".",
+			args:        []argSpec{areasArg},
+			examples:    []string{"sync", "sync 10"},
+			callback:    commandSync,
+		},
 	}
 }
@@ -1,6 +1,25 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	pngenc "image/png"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/mockapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/output"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+)
 
 func TestCleanInput(t *testing.T) {
 	cases := []struct {
@@ -13,16 +32,47 @@ func TestCleanInput(t *testing.T) {
 		},
 		{
 			input:    "     HELLO   WoRlD ",
-			expected: []string{"hello", "world"},
+			expected: []string{"hello", "WoRlD"},
 		},
 		{
 			input:    "three words here",
 			expected: []string{"three", "words", "here"},
 		},
+		{
+			input:    `export "My Pokedex.json"`,
+			expected: []string{"export", "My Pokedex.json"},
+		},
+		{
+			input:    `say 'it''s' "a \"quote\" and a \\" 'no \escapes'`,
+			expected: []string{"say", "its", `a "quote" and a \`, `no \escapes`},
+		},
+		{
+			input:    `load my\ file.json \'x\'`,
+			expected: []string{"load", "my file.json", "'x'"},
+		},
+		{
+			input:    `name --as="Ash Ketchum" --note=''`,
+			expected: []string{"name", "--as=Ash Ketchum", "--note="},
+		},
+		{
+			input:    `note "" ''`,
+			expected: []string{"note", "", ""},
+		},
+		{
+			input:    "tabs\tand\nnewlines",
+			expected: []string{"tabs", "and", "newlines"},
+		},
+		{
+			input:    "   ",
+			expected: nil,
+		},
 	}
 
 	for _, c := range cases {
-		actual := cleanInput(c.input)
+		actual, err := cleanInput(c.input)
+		if err != nil {
+			t.Errorf("%q: %v", c.input, err)
+		}
         if len(actual) != len(c.expected) {
             t.Errorf("cleanInput output is not the right length")
         }
@@ -35,3 +85,663 @@ func TestCleanInput(t *testing.T) {
 		}
 	}
 }
+
+// newTestConfig starts a session against the mock API, which is shut down when the test ends.
+// The API URL in opts is replaced with the mock's.
+func newTestConfig(t *testing.T, opts replOptions) *config {
+	t.Helper()
+	handler, err := mockapi.NewHandler(mockapi.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	opts.settings.APIURL = server.URL + "/api/v2"
+	userConfig, _ := ReplInitialisation(opts)
+	t.Cleanup(func() { shutdown(userConfig) })
+	return userConfig
+}
+
+func TestRunCommand(t *testing.T) {
+	userConfig := newTestConfig(t, replOptions{})
+
+	cases := []struct {
+		input    string
+		expected int
+	}{
+		{input: "map", expected: EXIT_OK},
+		{input: "explore canalave-city-area", expected: EXIT_OK},
+		{input: "EXPLORE Canalave-City-Area", expected: EXIT_OK},
+		{input: `explore "canalave-city-area"`, expected: EXIT_OK},
+		{input: "CACHE Stats -o JSON", expected: EXIT_OK},
+		{input: "explore nowhere-at-all", expected: EXIT_COMMAND_FAILED},
+		{input: "explore", expected: EXIT_USAGE},
+		{input: "explore canalave-city-area pastoria-city-area", expected: EXIT_USAGE},
+		{input: "cache list --stale", expected: EXIT_OK},
+		{input: "cache list --fresh", expected: EXIT_USAGE},
+		{input: "cache shrink", expected: EXIT_USAGE},
+		{input: "help explore", expected: EXIT_OK},
+		{input: "help fly", expected: EXIT_COMMAND_FAILED},
+		{input: "quit-not-really", expected: EXIT_USAGE},
+		{input: "inspect pikachu", expected: EXIT_COMMAND_FAILED},
+		{input: "fly canalave-city-area", expected: EXIT_USAGE},
+	}
+	for _, c := range cases {
+		if code := runCommand(userConfig, mustCleanInput(t, c.input)); code != c.expected {
+			t.Errorf("%q: exit code %d, expected %d", c.input, code, c.expected)
+		}
+	}
+}
+
+func TestCatchAllKeepsGoing(t *testing.T) {
+	userConfig := newTestConfig(t, replOptions{})
+	var out strings.Builder
+	userConfig.Out = &out
+
+	userConfig.LastEncounters = []string{"missingno", "pikachu"}
+	if code := runCommand(userConfig, mustCleanInput(t, "catch --all -o json")); code != EXIT_COMMAND_FAILED {
+		t.Errorf("expected exit code %d when one Pokemon can't be fetched, got %d", EXIT_COMMAND_FAILED, code)
+	}
+	var result catchAttempts
+	if err := json.Unmarshal([]byte(out.String()), &result); err != nil {
+		t.Fatalf("expected the attempts as JSON, got %q: %v", out.String(), err)
+	}
+	if len(result.Attempts) != 2 || result.Attempts[0].Error == "" || result.Attempts[1].Error != "" || result.Attempts[1].Roll == 0 {
+		t.Errorf("expected a failure for missingno and a throw at pikachu, got %+v", result.Attempts)
+	}
+}
+
+func TestPrefetchFailures(t *testing.T) {
+	// the first page of areas loads, but everything in it fails
+	pages, err := mockapi.NewHandler(mockapi.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, err := mockapi.NewHandler(mockapi.Options{ErrorRate: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/location-area/" {
+			pages.ServeHTTP(w, r)
+			return
+		}
+		failing.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	userConfig, _ := ReplInitialisation(replOptions{settings: settings{APIURL: server.URL + "/api/v2"}})
+	defer shutdown(userConfig)
+
+	if code := runCommand(userConfig, mustCleanInput(t, "prefetch 2")); code != EXIT_COMMAND_FAILED {
+		t.Errorf("expected exit code %d when areas fail to load, got %d", EXIT_COMMAND_FAILED, code)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	cases := []struct {
+		name     string
+		script   string
+		expected int
+		mapRan   bool
+	}{
+		{
+			name:     "comments and blank lines",
+			script:   "# look around\n\nexplore canalave-city-area\nmap\n",
+			expected: EXIT_OK,
+			mapRan:   true,
+		},
+		{
+			name:     "failures without set -e",
+			script:   "explore nowhere-at-all\nmap\n",
+			expected: EXIT_COMMAND_FAILED,
+			mapRan:   true,
+		},
+		{
+			name:     "set -e stops at the first failure",
+			script:   "set -e\nfly\nmap\n",
+			expected: EXIT_USAGE,
+			mapRan:   false,
+		},
+		{
+			name:     "set +e",
+			script:   "set -e\nset +e\ninspect pikachu\nmap",
+			expected: EXIT_COMMAND_FAILED,
+			mapRan:   true,
+		},
+		{
+			name:     "exit keeps the exit code so far",
+			script:   "fly\nexit\nmap\n",
+			expected: EXIT_USAGE,
+			mapRan:   false,
+		},
+		{
+			name:     "quit ends the script",
+			script:   "map\nquit\nfly\n",
+			expected: EXIT_OK,
+			mapRan:   true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			userConfig := newTestConfig(t, replOptions{})
+			firstPage := userConfig.Next
+
+			if code := runScript(userConfig, strings.NewReader(c.script)); code != c.expected {
+				t.Errorf("exit code %d, expected %d", code, c.expected)
+			}
+			if mapRan := userConfig.Next != firstPage; mapRan != c.mapRan {
+				t.Errorf("map ran: %v, expected %v", mapRan, c.mapRan)
+			}
+		})
+	}
+}
+
+func TestTakeOutputFlag(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string
+		format   output.Format
+		fails    bool
+	}{
+		{input: "map", expected: []string{"map"}},
+		{input: "map --output json", expected: []string{"map"}, format: output.FormatJSON},
+		{input: "explore -o yaml canalave-city-area", expected: []string{"explore", "canalave-city-area"}, format: output.FormatYAML},
+		{input: "pokedex --output=csv", expected: []string{"pokedex"}, format: output.FormatCSV},
+		{input: "map --output", fails: true},
+		{input: "map -o xml", fails: true},
+		{input: "-o json", fails: true},
+	}
+	for _, c := range cases {
+		actual, format, err := takeOutputFlag(mustCleanInput(t, c.input))
+		if c.fails {
+			if err == nil {
+				t.Errorf("%q: expected an error", c.input)
+			}
+			continue
+		}
+		if err != nil || format != c.format || !slices.Equal(actual, c.expected) {
+			t.Errorf("%q: got %v, %q, %v; expected %v, %q", c.input, actual, format, err, c.expected, c.format)
+		}
+	}
+}
+
+func TestRunCommandJSONOutput(t *testing.T) {
+	userConfig := newTestConfig(t, replOptions{})
+	var out strings.Builder
+	userConfig.Out = &out
+
+	if code := runCommand(userConfig, mustCleanInput(t, "explore pallet-town-area --output json")); code != EXIT_OK {
+		t.Fatalf("exit code %d", code)
+	}
+	var encounters encounterList
+	if err := json.Unmarshal([]byte(out.String()), &encounters); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if encounters.Area != "pallet-town-area" || !slices.Equal(encounters.Pokemon, []string{"bulbasaur", "charmander", "squirtle"}) {
+		t.Errorf("got %+v", encounters)
+	}
+	if userConfig.Output != output.FormatText {
+		t.Errorf("--output changed the session format to %q", userConfig.Output)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	userConfig, _ := ReplInitialisation(replOptions{})
+	defer shutdown(userConfig)
+	userConfig.rememberAreas([]string{"canalave-city-area", "eterna-city-area"})
+	userConfig.rememberAreas([]string{"eterna-city-area", "oreburgh-mine-1f"})
+	userConfig.LastEncounters = []string{"zubat", "geodude"}
+	userConfig.Pokedex["onix"] = caughtPokemon{Pokemon: pokeapi.Pokemon{Name: "onix"}}
+
+	cases := []struct {
+		input    []string
+		contains string
+		expected []string
+	}{
+		{input: []string{"ex"}, contains: "explore"},
+		{input: []string{"explore", ""}, expected: []string{"canalave-city-area", "eterna-city-area", "oreburgh-mine-1f"}},
+		{input: []string{"catch", "z"}, expected: []string{"zubat", "geodude"}},
+		{input: []string{"inspect", ""}, expected: []string{"onix"}},
+		{input: []string{"cache", ""}, expected: []string{"stats", "list", "clear", "evict"}},
+		{input: []string{"map", "-o", ""}, contains: "json"},
+		{input: []string{"explore", "canalave-city-area", ""}, expected: nil},
+	}
+	for _, c := range cases {
+		actual := userConfig.complete(c.input)
+		if c.contains != "" {
+			if !slices.Contains(actual, c.contains) {
+				t.Errorf("%q: %v does not contain %q", c.input, actual, c.contains)
+			}
+			continue
+		}
+		if !slices.Equal(actual, c.expected) {
+			t.Errorf("%q: got %v, expected %v", c.input, actual, c.expected)
+		}
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	userConfig := newTestConfig(t, replOptions{})
+	userConfig.Pokedex["geodude"] = caughtPokemon{Pokemon: pokeapi.Pokemon{Name: "geodude"}}
+
+	userConfig.Aliases["scout"] = "explore $1; catch --all"
+
+	if msg := unknownCommandMessage(userConfig, "mpa"); msg != "Unknown command 'mpa'. Did you mean 'map'?" {
+		t.Errorf("got %q", msg)
+	}
+	if msg := unknownCommandMessage(userConfig, "fly"); !strings.Contains(msg, `Run "help"`) {
+		t.Errorf("got %q", msg)
+	}
+	if msg := unknownCommandMessage(userConfig, "scuot"); msg != "Unknown command 'scuot'. Did you mean 'scout'?" {
+		t.Errorf("got %q", msg)
+	}
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "catch charmandr", expected: "No Pokemon 'charmandr' — did you mean 'charmander'?"},
+		{input: "catch mewtwo", expected: "No Pokemon 'mewtwo'"},
+		{input: "explore canalave-city-are", expected: "No location area 'canalave-city-are' — did you mean 'canalave-city-area'?"},
+		{input: "inspect geodud", expected: "geodud is not in your Pokedex. Did you mean 'geodude'?"},
+	}
+	for _, c := range cases {
+		words := mustCleanInput(t, c.input)
+		command, _ := lookupCommand(words[0])
+		err := command.run(userConfig, words[1:])
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("%q: got %v, expected %q", c.input, err, c.expected)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	command := cliCommand{
+		name: "release",
+		args: []argSpec{
+			{name: "pokemon name"},
+			{name: "where", optional: true, choices: []string{"wild", "daycare"}},
+		},
+		flags: []flagSpec{
+			{name: "quiet", short: "q", kind: flagBool},
+			{name: "count", short: "n", kind: flagInt, value: "1"},
+			{name: "note", kind: flagString},
+		},
+	}
+	cases := []struct {
+		input string
+		args  map[string]string
+		flags map[string]string
+		fails bool
+	}{
+		{input: "pikachu", args: map[string]string{"pokemon name": "pikachu"}, flags: map[string]string{"count": "1"}},
+		{input: "pikachu daycare -q", args: map[string]string{"pokemon name": "pikachu", "where": "daycare"}, flags: map[string]string{"count": "1", "quiet": "true"}},
+		{input: "--count 3 pikachu --note=bye", args: map[string]string{"pokemon name": "pikachu"}, flags: map[string]string{"count": "3", "note": "bye"}},
+		{input: "-n -2 pikachu --quiet=false", args: map[string]string{"pokemon name": "pikachu"}, flags: map[string]string{"count": "-2", "quiet": "false"}},
+		{input: "-- -q", args: map[string]string{"pokemon name": "-q"}, flags: map[string]string{"count": "1"}},
+		{input: "", fails: true},
+		{input: "pikachu zoo", fails: true},
+		{input: "pikachu wild extra", fails: true},
+		{input: "pikachu --count many", fails: true},
+		{input: "pikachu --count", fails: true},
+		{input: "pikachu --quiet=maybe", fails: true},
+		{input: "pikachu --loud", fails: true},
+	}
+	for _, c := range cases {
+		in, err := command.parse(mustCleanInput(t, c.input))
+		if c.fails {
+			if !isUsageError(err) {
+				t.Errorf("%q: expected a usage error, got %v", c.input, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", c.input, err)
+			continue
+		}
+		if !maps.Equal(in.args, c.args) || !maps.Equal(in.flags, c.flags) {
+			t.Errorf("%q: got args %v flags %v, expected args %v flags %v", c.input, in.args, in.flags, c.args, c.flags)
+		}
+	}
+}
+
+func TestHelpIsSorted(t *testing.T) {
+	commands := sortedCommands()
+	for i := 1; i < len(commands); i++ {
+		if commands[i-1].name > commands[i].name {
+			t.Errorf("%s listed before %s", commands[i-1].name, commands[i].name)
+		}
+	}
+}
+
+func TestCleanInputErrors(t *testing.T) {
+	for _, input := range []string{`say "hello`, `say 'hello`, `say hello\`} {
+		if words, err := cleanInput(input); err == nil {
+			t.Errorf("%q: expected an error, got %q", input, words)
+		}
+	}
+}
+
+// FuzzCleanInput checks that any input either fails to parse or splits into words that, quoted
+// again, split back into the same words.
+func FuzzCleanInput(f *testing.F) {
+	for _, seed := range []string{"map", "  HELLO  WoRlD ", `explore "a b" 'c d' e\ f`, `--x="y z"`, `"`, `\\`, `'\''`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		words, err := tokenize(input)
+		if err != nil {
+			return
+		}
+		quoted := make([]string, len(words))
+		for i, w := range words {
+			quoted[i] = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+		}
+		again, err := tokenize(strings.Join(quoted, " "))
+		if err != nil {
+			t.Fatalf("requoted %q: %v", words, err)
+		}
+		if !slices.Equal(words, again) && !(len(words) == 0 && len(again) == 0) {
+			t.Errorf("%q split into %q, which requoted split into %q", input, words, again)
+		}
+	})
+}
+
+func mustCleanInput(t *testing.T, input string) []string {
+	t.Helper()
+	words, err := cleanInput(input)
+	if err != nil {
+		t.Fatalf("cleanInput(%q): %v", input, err)
+	}
+	return words
+}
+
+func TestExpandAlias(t *testing.T) {
+	cases := []struct {
+		expansion string
+		args      []string
+		expected  [][]string
+		fails     bool
+	}{
+		{expansion: "explore", args: []string{"canalave-city-area"}, expected: [][]string{{"explore", "canalave-city-area"}}},
+		{expansion: "explore $1; catch --all", args: []string{"canalave-city-area"}, expected: [][]string{{"explore", "canalave-city-area"}, {"catch", "--all"}}},
+		{expansion: "catch $2; catch $1", args: []string{"zubat", "onix"}, expected: [][]string{{"catch", "onix"}, {"catch", "zubat"}}},
+		{expansion: "inspect $@", args: []string{"$2", "x"}, expected: [][]string{{"inspect", "$2", "x"}}},
+		{expansion: `cache evict "https://pokeapi.co/api/v2/pokemon/$1"`, args: []string{"onix"}, expected: [][]string{{"cache", "evict", "https://pokeapi.co/api/v2/pokemon/onix"}}},
+		{expansion: "map; mapb ';'", expected: [][]string{{"map"}, {"mapb", ";"}}},
+		{expansion: "explore $1; catch --all", fails: true},
+		{expansion: "explore $1", args: []string{"a", "b"}, fails: true},
+	}
+	for _, c := range cases {
+		actual, err := expandAlias("test", c.expansion, c.args)
+		if c.fails {
+			if err == nil {
+				t.Errorf("%q %q: expected an error", c.expansion, c.args)
+			}
+			continue
+		}
+		if err != nil || !slices.EqualFunc(actual, c.expected, slices.Equal) {
+			t.Errorf("%q %q: got %q, %v; expected %q", c.expansion, c.args, actual, err, c.expected)
+		}
+	}
+}
+
+func TestAliases(t *testing.T) {
+	aliasFile := t.TempDir() + "/pokedex/aliases"
+	userConfig := newTestConfig(t, replOptions{aliasFile: aliasFile})
+
+	cases := []struct {
+		input    string
+		expected int
+	}{
+		{input: "e pallet-town-area", expected: EXIT_OK},
+		{input: "alias scout 'explore $1; catch --all'", expected: EXIT_OK},
+		{input: "SCOUT Pallet-Town-Area", expected: EXIT_OK},
+		{input: "scout", expected: EXIT_USAGE},
+		{input: "scout nowhere-at-all", expected: EXIT_COMMAND_FAILED},
+		{input: "alias map explore", expected: EXIT_COMMAND_FAILED},
+		{input: "alias e explore", expected: EXIT_COMMAND_FAILED},
+		{input: "alias again 'scout pallet-town-area'", expected: EXIT_COMMAND_FAILED},
+		{input: "again", expected: EXIT_USAGE},
+		{input: "alias loop 'map; loop'", expected: EXIT_COMMAND_FAILED},
+		{input: "alias scout2 'exploer $1'", expected: EXIT_USAGE},
+		{input: "alias", expected: EXIT_OK},
+		{input: "help scout", expected: EXIT_OK},
+		{input: "unalias scout2", expected: EXIT_COMMAND_FAILED},
+		{input: "unalias e", expected: EXIT_COMMAND_FAILED},
+		{input: "catch", expected: EXIT_USAGE},
+		{input: "catch --all onix", expected: EXIT_USAGE},
+	}
+	for _, c := range cases {
+		if code := runCommand(userConfig, mustCleanInput(t, c.input)); code != c.expected {
+			t.Errorf("%q: exit code %d, expected %d", c.input, code, c.expected)
+		}
+	}
+	if !slices.Equal(userConfig.LastEncounters, []string{"bulbasaur", "charmander", "squirtle"}) {
+		t.Errorf("scout didn't explore, last encounters %q", userConfig.LastEncounters)
+	}
+
+	saved, err := loadAliases(aliasFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(saved, map[string]string{"scout": "explore $1; catch --all"}) {
+		t.Errorf("saved aliases %q", saved)
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	dir := t.TempDir()
+	configFile := dir + "/config.json"
+	if err := os.WriteFile(configFile, []byte(`{"cache_ttl": "5m", "seed": 42, "output": "json", "game_version": "Diamond"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"POKEDEX_OUTPUT": "yaml", "POKEDEX_CACHE_SIZE_MB": "8"}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	if defaults := defaultSettings(); defaults.SavePath != "" {
+		t.Errorf("expected caught Pokemon not to be saved unless asked, got save_path %q", defaults.SavePath)
+	}
+	s, sources, err := loadSettings(configFile, lookupEnv, map[string]string{"output": "csv", "save_path": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := defaultSettings()
+	expected.CacheTTL = 5 * time.Minute
+	expected.CacheSizeMB = 8
+	expected.GameVersion = "diamond"
+	expected.Output = output.FormatCSV
+	expected.SavePath = ""
+	expected.Seed = 42
+	if s != expected {
+		t.Errorf("got %+v, expected %+v", s, expected)
+	}
+	expectedSources := map[string]string{
+		"api_url":       sourceDefault,
+		"cache_ttl":     sourceConfigFile,
+		"cache_size_mb": "$POKEDEX_CACHE_SIZE_MB",
+		"game_version":  sourceConfigFile,
+		"output":        "-output",
+		"color":         sourceDefault,
+		"save_path":     "-save-path",
+		"seed":          sourceConfigFile,
+	}
+	if !maps.Equal(sources, expectedSources) {
+		t.Errorf("sources %v", sources)
+	}
+
+	if _, _, err := loadSettings(dir+"/missing.json", lookupEnv, nil); err != nil {
+		t.Errorf("a missing config file: %v", err)
+	}
+	failing := []struct {
+		file  string
+		env   map[string]string
+		flags map[string]string
+	}{
+		{file: `{"cache_ttl": "soon"}`},
+		{file: `{"colour": "never"}`},
+		{file: `["not", "an", "object"]`},
+		{file: `{}`, env: map[string]string{"POKEDEX_SEED": "lucky"}},
+		{file: `{}`, flags: map[string]string{"color": "sometimes"}},
+		{file: `{}`, flags: map[string]string{"cache_size_mb": "0"}},
+	}
+	for _, c := range failing {
+		if err := os.WriteFile(configFile, []byte(c.file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		env = c.env
+		if _, _, err := loadSettings(configFile, lookupEnv, c.flags); err == nil {
+			t.Errorf("%s %v %v: expected an error", c.file, c.env, c.flags)
+		}
+	}
+}
+
+func TestSettingsInSession(t *testing.T) {
+	s := settings{SavePath: t.TempDir() + "/pokedex.json", Seed: 7, GameVersion: "platinum"}
+
+	// the same seed rolls the same numbers
+	rolls := func() []int {
+		userConfig, _ := ReplInitialisation(replOptions{settings: settings{Seed: 7}})
+		defer shutdown(userConfig)
+		var r []int
+		for range 5 {
+			r = append(r, userConfig.Rand.Intn(9))
+		}
+		return r
+	}
+	if first, second := rolls(), rolls(); !slices.Equal(first, second) {
+		t.Errorf("seed 7 rolled %v, then %v", first, second)
+	}
+
+	userConfig := newTestConfig(t, replOptions{settings: s})
+	var out strings.Builder
+	userConfig.Out = &out
+	if code := runCommand(userConfig, mustCleanInput(t, "explore pallet-town-area -o json")); code != EXIT_OK {
+		t.Fatalf("explore: exit code %d", code)
+	}
+	if !strings.Contains(out.String(), `"pokemon": []`) {
+		t.Errorf("explore in platinum found %s", out.String())
+	}
+	for range 20 {
+		runCommand(userConfig, mustCleanInput(t, "explore pallet-town-area --game-version diamond"))
+		runCommand(userConfig, mustCleanInput(t, "catch --all"))
+	}
+	caught := slices.Sorted(maps.Keys(userConfig.Pokedex))
+	if len(caught) == 0 {
+		t.Fatal("nothing was caught in 20 tries")
+	}
+	if code := runCommand(userConfig, mustCleanInput(t, "config show")); code != EXIT_OK {
+		t.Errorf("config show: exit code %d", code)
+	}
+	shutdown(userConfig)
+
+	// the next session starts with the Pokemon caught in this one
+	userConfig = newTestConfig(t, replOptions{settings: s})
+	if reloaded := slices.Sorted(maps.Keys(userConfig.Pokedex)); !slices.Equal(reloaded, caught) {
+		t.Errorf("caught %v, but the next session has %v", caught, reloaded)
+	}
+}
+
+func TestColorOutput(t *testing.T) {
+	userConfig, _ := ReplInitialisation(replOptions{})
+	defer shutdown(userConfig)
+	var pikachu caughtPokemon
+	if err := json.Unmarshal([]byte(`{"name": "pikachu", "shiny": true, "types": [{"slot": 1, "type": {"name": "electric"}}], "stats": [{"base_stat": 35, "stat": {"name": "hp"}}]}`), &pikachu); err != nil {
+		t.Fatal(err)
+	}
+	userConfig.Pokedex["pikachu"] = pikachu
+
+	cases := []struct {
+		name        string
+		input       string
+		palette     ansi.Palette
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "colored",
+			input:    "inspect pikachu",
+			palette:  ansi.Palette{Enabled: true, TrueColor: true},
+			contains: []string{"\x1b[48;2;247;208;44m", shinyMarker, "hp  35 \x1b[38;2;243;68;68m███▎"},
+		},
+		{
+			name:        "plain",
+			input:       "inspect pikachu",
+			contains:    []string{"Name: pikachu (shiny)\n", "-hp: 35\n"},
+			notContains: []string{"\x1b["},
+		},
+		{
+			name:        "json stays plain",
+			input:       "inspect pikachu -o json",
+			palette:     ansi.Palette{Enabled: true, TrueColor: true},
+			contains:    []string{`"shiny": true`},
+			notContains: []string{"\x1b["},
+		},
+		{
+			name:     "pokedex marks shiny Pokemon",
+			input:    "pokedex",
+			palette:  ansi.Palette{Enabled: true},
+			contains: []string{" - pikachu \x1b[38;5;220m" + shinyMarker},
+		},
+	}
+	for _, c := range cases {
+		var out strings.Builder
+		userConfig.Out = &out
+		userConfig.Palette = c.palette
+		if code := runCommand(userConfig, mustCleanInput(t, c.input)); code != EXIT_OK {
+			t.Fatalf("%s: exit code %d", c.name, code)
+		}
+		for _, s := range c.contains {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("%s: %q is missing from:\n%s", c.name, s, out.String())
+			}
+		}
+		for _, s := range c.notContains {
+			if strings.Contains(out.String(), s) {
+				t.Errorf("%s: %q shouldn't be in:\n%s", c.name, s, out.String())
+			}
+		}
+	}
+}
+
+func TestSprites(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	img.Set(1, 1, color.NRGBA{B: 255, A: 255})
+	var png bytes.Buffer
+	if err := pngenc.Encode(&png, img); err != nil {
+		t.Fatal(err)
+	}
+	sprites := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(png.Bytes()) }))
+	defer sprites.Close()
+
+	userConfig := newTestConfig(t, replOptions{})
+	pikachu := caughtPokemon{Pokemon: pokeapi.Pokemon{Name: "pikachu", Height: 4}}
+	pikachu.Sprites.FrontDefault = sprites.URL + "/sprites/pikachu.png"
+	userConfig.Pokedex["pikachu"] = pikachu
+	userConfig.Pokedex["onix"] = caughtPokemon{Pokemon: pokeapi.Pokemon{Name: "onix"}}
+
+	cases := []struct {
+		input    string
+		expected int
+		output   string
+	}{
+		{input: "inspect pikachu --sprite", expected: EXIT_OK, output: "▀▄  Name: pikachu\n    Height: 4\n"},
+		{input: "inspect onix -s", expected: EXIT_OK, output: "Name: onix\n"},
+		{input: "sprite mewtwo --shiny", expected: EXIT_COMMAND_FAILED},
+		{input: "sprite pikachu --front", expected: EXIT_USAGE},
+	}
+	for _, c := range cases {
+		var out strings.Builder
+		userConfig.Out = &out
+		if code := runCommand(userConfig, mustCleanInput(t, c.input)); code != c.expected {
+			t.Errorf("%q: exit code %d, expected %d", c.input, code, c.expected)
+		}
+		if !strings.HasPrefix(out.String(), c.output) {
+			t.Errorf("%q: output\n%s\nexpected it to start with\n%s", c.input, out.String(), c.output)
+		}
+	}
+}
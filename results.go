@@ -0,0 +1,683 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokecache"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/sprite"
+)
+
+// The types in this file are what commands return, rendered by the output package in the
+// format the user picked. Their Text methods keep the text format the same as it has always been,
+// and ColorText methods dress it up for terminals that show colors.
+
+// Colors and sizes used by the ColorText methods.
+var (
+	colorGood  = ansi.RGB{R: 0x23, G: 0xCD, B: 0x5E}
+	colorBad   = ansi.RGB{R: 0xF3, G: 0x44, B: 0x44}
+	colorShiny = ansi.RGB{R: 0xFF, G: 0xD7, B: 0x00}
+)
+
+const (
+	shinyMarker  = "✨"
+	maxBaseStat  = 255 // the highest base stat a Pokemon can have, the full length of a stat bar
+	statBarWidth = 24
+)
+
+// message is a result that is just a sentence, like "Removed 3 entries from the cache."
+type message struct {
+	Message string `json:"message"`
+}
+
+func (m message) Text() string { return m.Message + "\n" }
+
+// commandList is the result of help.
+type commandList struct {
+	Commands []commandSummary `json:"commands"`
+}
+
+type commandSummary struct {
+	Name        string `json:"name"`
+	Usage       string `json:"usage"`
+	Description string `json:"description"`
+}
+
+func (l commandList) Text() string {
+	var b strings.Builder
+	b.WriteString("Welcome to the Pokedex!\nUsage:\n\n")
+	for _, c := range l.Commands {
+		b.WriteString(c.Usage + ": " + c.Description + "\n")
+	}
+	b.WriteString("\nRun \"help <command>\" for details about a command.\n")
+	return b.String()
+}
+
+func (l commandList) Columns() []string { return []string{"name", "usage", "description"} }
+
+func (l commandList) Rows() [][]string {
+	rows := make([][]string, len(l.Commands))
+	for i, c := range l.Commands {
+		rows[i] = []string{c.Name, c.Usage, c.Description}
+	}
+	return rows
+}
+
+// commandUsage is the result of "help <command>".
+type commandUsage struct {
+	Name        string       `json:"name"`
+	Usage       string       `json:"usage"`
+	Description string       `json:"description"`
+	Aliases     []string     `json:"aliases"`
+	Arguments   []helpOption `json:"arguments"`
+	Flags       []helpOption `json:"flags"`
+	Examples    []string     `json:"examples"`
+}
+
+// helpOption describes one argument or flag in detailed help.
+type helpOption struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func newCommandUsage(c cliCommand) commandUsage {
+	h := commandUsage{
+		Name:        c.name,
+		Usage:       c.usage(),
+		Description: c.description,
+		Aliases:     append([]string{}, c.aliases...),
+		Arguments:   []helpOption{},
+		Flags:       []helpOption{},
+		Examples:    append([]string{}, c.examples...),
+	}
+	for _, a := range c.args {
+		description := a.description
+		if a.optional {
+			description += " (optional)"
+		}
+		h.Arguments = append(h.Arguments, helpOption{Name: a.name, Description: description})
+	}
+	for _, f := range c.flags {
+		name := "--" + f.name
+		if f.short != "" {
+			name = "-" + f.short + ", " + name
+		}
+		switch f.kind {
+		case flagInt:
+			name += " <number>"
+		case flagString:
+			name += " <value>"
+		}
+		description := f.description
+		if f.value != "" {
+			description += fmt.Sprintf(" (default %s)", f.value)
+		}
+		h.Flags = append(h.Flags, helpOption{Name: name, Description: description})
+	}
+	// every command takes the output format flag, handled before the command runs
+	h.Flags = append(h.Flags, helpOption{Name: "-o, --output <format>", Description: "show the result as text, table, json, yaml or csv"})
+	return h
+}
+
+func (h commandUsage) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s\n\n%s\n", h.Usage, h.Description)
+	writeOptions := func(title string, options []helpOption) {
+		if len(options) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		width := 0
+		for _, o := range options {
+			width = max(width, len(o.Name))
+		}
+		for _, o := range options {
+			fmt.Fprintf(&b, "  %-*s  %s\n", width, o.Name, o.Description)
+		}
+	}
+	writeOptions("Arguments", h.Arguments)
+	writeOptions("Flags", h.Flags)
+	if len(h.Aliases) > 0 {
+		fmt.Fprintf(&b, "\nAliases: %s\n", strings.Join(h.Aliases, ", "))
+	}
+	if len(h.Examples) > 0 {
+		fmt.Fprintln(&b, "\nExamples:")
+		for _, e := range h.Examples {
+			fmt.Fprintln(&b, "  "+e)
+		}
+	}
+	return b.String()
+}
+
+// aliasList is the result of alias: the aliases and the commands they stand for.
+type aliasList struct {
+	Aliases []aliasEntry `json:"aliases"`
+}
+
+type aliasEntry struct {
+	Name     string `json:"name"`
+	Commands string `json:"commands"`
+	BuiltIn  bool   `json:"built_in"`
+}
+
+// newAliasList lists the user's aliases in alphabetical order, after the built-in ones if
+// withBuiltIn is set.
+func newAliasList(aliases map[string]string, withBuiltIn bool) aliasList {
+	l := aliasList{Aliases: []aliasEntry{}}
+	if withBuiltIn {
+		for _, command := range sortedCommands() {
+			for _, alias := range command.aliases {
+				l.Aliases = append(l.Aliases, aliasEntry{Name: alias, Commands: command.name, BuiltIn: true})
+			}
+		}
+		sort.Slice(l.Aliases, func(i, j int) bool { return l.Aliases[i].Name < l.Aliases[j].Name })
+	}
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l.Aliases = append(l.Aliases, aliasEntry{Name: name, Commands: aliases[name]})
+	}
+	return l
+}
+
+func (l aliasList) Text() string {
+	var b strings.Builder
+	width := 0
+	for _, a := range l.Aliases {
+		width = max(width, len(a.Name))
+	}
+	for _, a := range l.Aliases {
+		note := ""
+		if a.BuiltIn {
+			note = " (built-in)"
+		}
+		fmt.Fprintf(&b, "%-*s = %s%s\n", width, a.Name, a.Commands, note)
+	}
+	return b.String()
+}
+
+func (l aliasList) Columns() []string { return []string{"name", "commands", "built_in"} }
+
+func (l aliasList) Rows() [][]string {
+	rows := make([][]string, len(l.Aliases))
+	for i, a := range l.Aliases {
+		rows[i] = []string{a.Name, a.Commands, strconv.FormatBool(a.BuiltIn)}
+	}
+	return rows
+}
+
+// settingsList is the result of "config show": every setting, its value and where it came from.
+type settingsList struct {
+	File     string         `json:"config_file"`
+	Settings []settingEntry `json:"settings"`
+}
+
+type settingEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+func (l settingsList) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Config file: %s\n\n", l.File)
+	width := 0
+	for _, s := range l.Settings {
+		width = max(width, len(s.Key))
+	}
+	for _, s := range l.Settings {
+		value := s.Value
+		if value == "" {
+			value = `""`
+		}
+		fmt.Fprintf(&b, "%-*s = %s (%s)\n", width, s.Key, value, s.Source)
+	}
+	return b.String()
+}
+
+func (l settingsList) Columns() []string { return []string{"key", "value", "source"} }
+
+func (l settingsList) Rows() [][]string {
+	rows := make([][]string, len(l.Settings))
+	for i, s := range l.Settings {
+		rows[i] = []string{s.Key, s.Value, s.Source}
+	}
+	return rows
+}
+
+// areaPage is the result of map and mapb: one page of location area names.
+type areaPage struct {
+	Areas    []string `json:"areas"`
+	Next     string   `json:"next"`
+	Previous string   `json:"previous"`
+}
+
+func (p areaPage) Text() string { return lines(p.Areas, "") }
+
+func (p areaPage) Columns() []string { return []string{"area"} }
+
+func (p areaPage) Rows() [][]string { return column(p.Areas) }
+
+// encounterList is the result of explore: the Pokemon that can be found in an area.
+type encounterList struct {
+	Area        string   `json:"area"`
+	GameVersion string   `json:"game_version,omitempty"` // the game the Pokemon were picked for, if any
+	Pokemon     []string `json:"pokemon"`
+}
+
+func (l encounterList) Text() string {
+	if len(l.Pokemon) == 0 && l.GameVersion != "" {
+		return fmt.Sprintf("No Pokemon can be found in %s in %s.\n", l.Area, l.GameVersion)
+	}
+	return lines(l.Pokemon, " - ")
+}
+
+func (l encounterList) Columns() []string { return []string{"pokemon"} }
+
+func (l encounterList) Rows() [][]string { return column(l.Pokemon) }
+
+// catchAttempt is the result of catch.
+type catchAttempt struct {
+	Pokemon        string `json:"pokemon"`
+	BaseExperience int    `json:"base_experience"`
+	Roll           int    `json:"roll"`
+	Caught         bool   `json:"caught"`
+	Shiny          bool   `json:"shiny"`
+
+	// Error says why no Pokeball could be thrown, when "catch --all" couldn't get the Pokemon
+	Error string `json:"error,omitempty"`
+}
+
+func (a catchAttempt) Text() string {
+	if a.Error != "" {
+		return fmt.Sprintf("Throwing a Pokeball at %s...\n%s\n", a.Pokemon, a.Error)
+	}
+	outcome := ";( Pokemon got away!"
+	if a.Caught {
+		outcome = ":) Pokemon caught!"
+	}
+	if a.Shiny {
+		outcome += " It's shiny!"
+	}
+	return fmt.Sprintf("Throwing a Pokeball at %s...\nBase Experience: %d\nrandChance: %d\n%s\n", a.Pokemon, a.BaseExperience, a.Roll, outcome)
+}
+
+func (a catchAttempt) ColorText(p ansi.Palette) string {
+	if a.Error != "" {
+		return fmt.Sprintf("Throwing a Pokeball at %s...\n%s\n", p.Bold(a.Pokemon), p.Fg(colorBad, a.Error))
+	}
+	outcome := p.Fg(colorBad, ";( Pokemon got away!")
+	if a.Caught {
+		outcome = p.Fg(colorGood, ":) Pokemon caught!")
+	}
+	if a.Shiny {
+		outcome += " " + p.Fg(colorShiny, shinyMarker+" It's shiny!")
+	}
+	return fmt.Sprintf("Throwing a Pokeball at %s...\nBase Experience: %d\nrandChance: %d\n%s\n", p.Bold(a.Pokemon), a.BaseExperience, a.Roll, outcome)
+}
+
+func (a catchAttempt) Columns() []string {
+	return []string{"pokemon", "base_experience", "roll", "caught", "shiny"}
+}
+
+func (a catchAttempt) Rows() [][]string {
+	return [][]string{{a.Pokemon, strconv.Itoa(a.BaseExperience), strconv.Itoa(a.Roll), strconv.FormatBool(a.Caught), strconv.FormatBool(a.Shiny)}}
+}
+
+// catchAttempts is the result of "catch --all": one attempt for each Pokemon.
+type catchAttempts struct {
+	Attempts []catchAttempt `json:"attempts"`
+}
+
+func (l catchAttempts) Text() string {
+	texts := make([]string, len(l.Attempts))
+	for i, a := range l.Attempts {
+		texts[i] = a.Text()
+	}
+	return strings.Join(texts, "\n")
+}
+
+func (l catchAttempts) ColorText(p ansi.Palette) string {
+	texts := make([]string, len(l.Attempts))
+	for i, a := range l.Attempts {
+		texts[i] = a.ColorText(p)
+	}
+	return strings.Join(texts, "\n")
+}
+
+func (l catchAttempts) Columns() []string { return append(catchAttempt{}.Columns(), "error") }
+
+func (l catchAttempts) Rows() [][]string {
+	var rows [][]string
+	for _, a := range l.Attempts {
+		row := a.Rows()[0]
+		rows = append(rows, append(row, a.Error))
+	}
+	return rows
+}
+
+// pokemonDetails is the result of inspect: the parts of a Pokemon people look at.
+type pokemonDetails struct {
+	Name   string        `json:"name"`
+	Height int           `json:"height"`
+	Weight int           `json:"weight"`
+	Stats  []pokemonStat `json:"stats"`
+	Types  []string      `json:"types"`
+	Shiny  bool          `json:"shiny"`
+
+	sprite image.Image // drawn beside the details when "inspect --sprite" asks for it
+}
+
+type pokemonStat struct {
+	Name     string `json:"name"`
+	BaseStat int    `json:"base_stat"`
+}
+
+func newPokemonDetails(p caughtPokemon) pokemonDetails {
+	d := pokemonDetails{
+		Name:   p.Name,
+		Height: p.Height,
+		Weight: p.Weight,
+		Stats:  []pokemonStat{},
+		Types:  []string{},
+		Shiny:  p.Shiny,
+	}
+	for _, s := range p.Stats {
+		d.Stats = append(d.Stats, pokemonStat{Name: s.Stat.Name, BaseStat: s.BaseStat})
+	}
+	for _, t := range p.Types {
+		d.Types = append(d.Types, t.Type.Name)
+	}
+	return d
+}
+
+func (d pokemonDetails) Text() string {
+	if d.sprite != nil {
+		withoutSprite := d
+		withoutSprite.sprite = nil
+		return besideSprite(d.sprite, ansi.Palette{}, withoutSprite.Text())
+	}
+	var b strings.Builder
+	if d.Shiny {
+		fmt.Fprintln(&b, "Name:", d.Name, "(shiny)")
+	} else {
+		fmt.Fprintln(&b, "Name:", d.Name)
+	}
+	fmt.Fprintln(&b, "Height:", d.Height)
+	fmt.Fprintln(&b, "Weight:", d.Weight)
+	fmt.Fprintln(&b, "Stats:")
+	for _, s := range d.Stats {
+		fmt.Fprintf(&b, "-%s: %d\n", s.Name, s.BaseStat)
+	}
+	fmt.Fprintln(&b, "Types:")
+	for _, t := range d.Types {
+		fmt.Fprintln(&b, "-", t)
+	}
+	return b.String()
+}
+
+// ColorText shows the types in their colors and each stat as a bar, out of the highest base
+// stat any Pokemon has.
+func (d pokemonDetails) ColorText(p ansi.Palette) string {
+	if d.sprite != nil {
+		withoutSprite := d
+		withoutSprite.sprite = nil
+		return besideSprite(d.sprite, p, withoutSprite.ColorText(p))
+	}
+	var b strings.Builder
+	name := p.Bold(d.Name)
+	if d.Shiny {
+		name += " " + p.Fg(colorShiny, shinyMarker+" shiny")
+	}
+	fmt.Fprintln(&b, name)
+	fmt.Fprintf(&b, "Height: %d  Weight: %d\n", d.Height, d.Weight)
+
+	badges := make([]string, len(d.Types))
+	for i, t := range d.Types {
+		if c, ok := ansi.TypeColor(t); ok {
+			badges[i] = p.Bg(c, " "+t+" ")
+		} else {
+			badges[i] = " " + t + " "
+		}
+	}
+	fmt.Fprintln(&b, "Types:", strings.Join(badges, " "))
+
+	fmt.Fprintln(&b, "Stats:")
+	width := 0
+	for _, s := range d.Stats {
+		width = max(width, len(s.Name))
+	}
+	for _, s := range d.Stats {
+		bar := p.Fg(ansi.StatColor(s.BaseStat), ansi.Bar(s.BaseStat, maxBaseStat, statBarWidth))
+		fmt.Fprintf(&b, "  %-*s %3d %s\n", width, s.Name, s.BaseStat, bar)
+	}
+	return b.String()
+}
+
+func (d pokemonDetails) Columns() []string { return []string{"field", "value"} }
+
+func (d pokemonDetails) Rows() [][]string {
+	rows := [][]string{
+		{"name", d.Name},
+		{"height", strconv.Itoa(d.Height)},
+		{"weight", strconv.Itoa(d.Weight)},
+	}
+	for _, s := range d.Stats {
+		rows = append(rows, []string{s.Name, strconv.Itoa(s.BaseStat)})
+	}
+	return append(rows, []string{"types", strings.Join(d.Types, " ")}, []string{"shiny", strconv.FormatBool(d.Shiny)})
+}
+
+// spriteImage is the result of sprite: one of a Pokemon's sprites, drawn with half-block
+// characters. The image itself is only shown in the text format.
+type spriteImage struct {
+	Pokemon string `json:"pokemon"`
+	URL     string `json:"url"`
+	Shiny   bool   `json:"shiny"`
+	Back    bool   `json:"back"`
+
+	img image.Image
+}
+
+// Text draws the sprite's silhouette, for when colors are turned off.
+func (s spriteImage) Text() string { return s.ColorText(ansi.Palette{}) }
+
+func (s spriteImage) ColorText(p ansi.Palette) string {
+	return strings.Join(sprite.Render(s.img, p), "\n") + "\n"
+}
+
+// besideSprite draws img to the left of text, lining up the first line of each.
+func besideSprite(img image.Image, p ansi.Palette, text string) string {
+	left := sprite.Render(img, p)
+	right := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	blank := strings.Repeat(" ", sprite.Width(img))
+	var b strings.Builder
+	for i := range max(len(left), len(right)) {
+		l, r := blank, ""
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		b.WriteString(strings.TrimRight(l+"  "+r, " ") + "\n")
+	}
+	return b.String()
+}
+
+// pokedexList is the result of pokedex: the names of every caught Pokemon.
+type pokedexList struct {
+	Pokemon []string `json:"pokemon"`
+	Shiny   []string `json:"shiny"` // the caught Pokemon that are shiny
+}
+
+func (l pokedexList) Text() string {
+	return l.ColorText(ansi.Palette{})
+}
+
+func (l pokedexList) ColorText(p ansi.Palette) string {
+	if len(l.Pokemon) == 0 {
+		return "Pokedex is empty. You haven't caught any Pokemon yet.\n"
+	}
+	var b strings.Builder
+	for _, name := range l.Pokemon {
+		switch {
+		case !slices.Contains(l.Shiny, name):
+			fmt.Fprintln(&b, " -", name)
+		case p.Enabled:
+			fmt.Fprintln(&b, " -", name, p.Fg(colorShiny, shinyMarker))
+		default:
+			fmt.Fprintln(&b, " -", name, "(shiny)")
+		}
+	}
+	return b.String()
+}
+
+func (l pokedexList) Columns() []string { return []string{"pokemon"} }
+
+func (l pokedexList) Rows() [][]string { return column(l.Pokemon) }
+
+// cacheStats is the result of "cache stats".
+type cacheStats struct {
+	Entries          int     `json:"entries"`
+	Bytes            int     `json:"bytes"`
+	RawBytes         int     `json:"uncompressed_bytes"`
+	Compressed       int     `json:"compressed_entries"`
+	CompressionRatio float64 `json:"compression_ratio"`
+	Hits             uint64  `json:"hits"`
+	StaleHits        uint64  `json:"stale_hits"`
+	Misses           uint64  `json:"misses"`
+	Evictions        uint64  `json:"evictions"`
+	Expirations      uint64  `json:"expirations"`
+	Coalesced        uint64  `json:"coalesced"`
+	Renewals         uint64  `json:"renewals"`
+}
+
+func newCacheStats(s pokecache.Stats) cacheStats {
+	return cacheStats{
+		Entries:          s.Entries,
+		Bytes:            s.Bytes,
+		RawBytes:         s.RawBytes,
+		Compressed:       s.Compressed,
+		CompressionRatio: s.CompressionRatio(),
+		Hits:             s.Hits,
+		StaleHits:        s.StaleHits,
+		Misses:           s.Misses,
+		Evictions:        s.Evictions,
+		Expirations:      s.Expirations,
+		Coalesced:        s.Coalesced,
+		Renewals:         s.Renewals,
+	}
+}
+
+func (s cacheStats) Text() string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "Entries:", s.Entries)
+	fmt.Fprintln(&b, "Bytes:", s.Bytes)
+	fmt.Fprintln(&b, "Uncompressed bytes:", s.RawBytes)
+	fmt.Fprintf(&b, "Compressed entries: %d (ratio %.1fx)\n", s.Compressed, s.CompressionRatio)
+	fmt.Fprintln(&b, "Hits:", s.Hits)
+	fmt.Fprintln(&b, "Stale hits:", s.StaleHits)
+	fmt.Fprintln(&b, "Misses:", s.Misses)
+	fmt.Fprintln(&b, "Evictions:", s.Evictions)
+	fmt.Fprintln(&b, "Expirations:", s.Expirations)
+	fmt.Fprintln(&b, "Coalesced:", s.Coalesced)
+	fmt.Fprintln(&b, "Renewals:", s.Renewals)
+	return b.String()
+}
+
+func (s cacheStats) Columns() []string { return []string{"metric", "value"} }
+
+func (s cacheStats) Rows() [][]string {
+	return [][]string{
+		{"entries", fmt.Sprint(s.Entries)},
+		{"bytes", fmt.Sprint(s.Bytes)},
+		{"uncompressed_bytes", fmt.Sprint(s.RawBytes)},
+		{"compressed_entries", fmt.Sprint(s.Compressed)},
+		{"compression_ratio", fmt.Sprintf("%.1f", s.CompressionRatio)},
+		{"hits", fmt.Sprint(s.Hits)},
+		{"stale_hits", fmt.Sprint(s.StaleHits)},
+		{"misses", fmt.Sprint(s.Misses)},
+		{"evictions", fmt.Sprint(s.Evictions)},
+		{"expirations", fmt.Sprint(s.Expirations)},
+		{"coalesced", fmt.Sprint(s.Coalesced)},
+		{"renewals", fmt.Sprint(s.Renewals)},
+	}
+}
+
+// cacheEntries is the result of "cache list".
+type cacheEntries struct {
+	Entries []cacheEntry `json:"entries"`
+}
+
+type cacheEntry struct {
+	Key        string  `json:"key"`
+	Size       int     `json:"size"`
+	AgeSeconds float64 `json:"age_seconds"`
+	Stale      bool    `json:"stale"`
+}
+
+func newCacheEntries(infos []pokecache.EntryInfo[string]) cacheEntries {
+	entries := cacheEntries{Entries: []cacheEntry{}}
+	for _, e := range infos {
+		entries.Entries = append(entries.Entries, cacheEntry{
+			Key:        e.Key,
+			Size:       e.Size,
+			AgeSeconds: e.Age.Round(time.Second).Seconds(),
+			Stale:      e.Stale,
+		})
+	}
+	return entries
+}
+
+func (l cacheEntries) Text() string {
+	if len(l.Entries) == 0 {
+		return "Cache is empty.\n"
+	}
+	var b strings.Builder
+	for _, e := range l.Entries {
+		staleMarker := ""
+		if e.Stale {
+			staleMarker = ", stale"
+		}
+		fmt.Fprintf(&b, " - %s (%d bytes, %s old%s)\n", e.Key, e.Size, time.Duration(e.AgeSeconds)*time.Second, staleMarker)
+	}
+	return b.String()
+}
+
+func (l cacheEntries) Columns() []string { return []string{"key", "size", "age_seconds", "stale"} }
+
+func (l cacheEntries) Rows() [][]string {
+	rows := make([][]string, len(l.Entries))
+	for i, e := range l.Entries {
+		rows[i] = []string{e.Key, strconv.Itoa(e.Size), fmt.Sprint(e.AgeSeconds), strconv.FormatBool(e.Stale)}
+	}
+	return rows
+}
+
+// lines puts each of items on a line of its own, after prefix.
+func lines(items []string, prefix string) string {
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString(prefix + item + "\n")
+	}
+	return b.String()
+}
+
+// column makes a one column table out of items.
+func column(items []string) [][]string {
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = []string{item}
+	}
+	return rows
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+)
+
+// caughtPokemon is a Pokemon in the user's Pokedex. It is saved as the Pokemon's own fields with
+// "shiny" added, so save files from before shiny Pokemon existed still load.
+type caughtPokemon struct {
+	pokeapi.Pokemon
+	Shiny bool `json:"shiny,omitempty"`
+}
+
+// loadPokedex reads the Pokemon caught in earlier sessions from the save file at path.
+// A missing file means nothing has been caught yet.
+func loadPokedex(path string) (map[string]caughtPokemon, error) {
+	pokedex := make(map[string]caughtPokemon)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return pokedex, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &pokedex); err != nil {
+		return nil, fmt.Errorf("error: save file %s is damaged: %w", path, err)
+	}
+	return pokedex, nil
+}
+
+// savePokedex writes the caught Pokemon to the save file at path. The file is replaced in
+// one step, so a crash while saving can't leave half a Pokedex behind.
+func savePokedex(path string, pokedex map[string]caughtPokemon) error {
+	data, err := json.Marshal(pokedex)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// runScript runs the commands in r, one per line, as if they were typed at the prompt, and
+// returns the exit code for the process.
+// Blank lines and lines starting with # are skipped. Each command is echoed to stderr before it runs.
+// "set -e" makes the script stop at the first failing command and "set +e" turns that off again.
+// Without "set -e" every command runs, and the exit code says whether any of them failed.
+func runScript(userConfig *config, r io.Reader) int {
+	scanner := bufio.NewScanner(r)
+	stopOnError := false
+	exitCode := EXIT_OK
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Pokedex > %s\n", line)
+
+		userPrompt, err := cleanInput(line)
+		if err == nil && userPrompt[0] == "set" && len(userPrompt) == 2 && (userPrompt[1] == "-e" || userPrompt[1] == "+e") {
+			stopOnError = userPrompt[1] == "-e"
+			continue
+		}
+
+		// exit ends the script, keeping the exit code of the commands before it
+		if err == nil {
+			if command, ok := lookupCommand(userPrompt[0]); ok && command.name == "exit" {
+				return exitCode
+			}
+		}
+
+		code := EXIT_USAGE
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			code = runCommand(userConfig, userPrompt)
+		}
+		if code == EXIT_REQUESTED { // an alias that ran exit
+			return exitCode
+		}
+		if code != EXIT_OK {
+			exitCode = code
+			if stopOnError {
+				fmt.Fprintf(os.Stderr, "stopping at line %d because of \"set -e\"\n", lineNumber)
+				return exitCode
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, fmt.Errorf("error reading script: %w", err))
+		return EXIT_COMMAND_FAILED
+	}
+	return exitCode
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal rather than a pipe or a file.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
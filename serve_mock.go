@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/mockapi"
+)
+
+// runServeMock runs the serve-mock subcommand: a local stand-in for PokeAPI that serves
+// recorded fixtures until the process is killed.
+func runServeMock(args []string) error {
+	fs := flag.NewFlagSet("serve-mock", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	latency := fs.Duration("latency", 0, "delay added before every response, e.g. 200ms")
+	errorRate := fs.Float64("error-rate", 0, "fraction of requests, from 0 to 1, answered with a 500 error")
+	seed := fs.Int64("seed", 1, "seed for choosing which requests fail")
+	fixtures := fs.String("fixtures", "", "directory of fixtures to serve instead of the built-in ones")
+	fs.Parse(args)
+
+	opts := mockapi.Options{
+		Latency:   *latency,
+		ErrorRate: *errorRate,
+		Seed:      *seed,
+	}
+	if *fixtures != "" {
+		opts.Fixtures = os.DirFS(*fixtures)
+	}
+	handler, err := mockapi.NewHandler(opts)
+	if err != nil {
+		return fmt.Errorf("error: could not load mock fixtures: %w", err)
+	}
+
+	fmt.Printf("Serving mock PokeAPI at http://%s%s\n", *addr, mockapi.APIPrefix)
+	fmt.Printf("Run the Pokedex against it with: pokedex -api-url http://%s/api/v2\n", *addr)
+	return http.ListenAndServe(*addr, handler)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/ansi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/output"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+)
+
+// settings are the user's preferences for a session. Each one comes from the first of these
+// that sets it: a command line flag, an environment variable, the config file, or its default.
+type settings struct {
+	APIURL      string        // root of the PokeAPI endpoints
+	CacheTTL    time.Duration // how long API responses stay fresh in the cache
+	CacheSizeMB int           // budget for the cached responses, after compression
+	GameVersion string        // only show encounters from this game, e.g. "diamond", or "" for every game
+	Output      output.Format // how command results are rendered unless a command says otherwise
+	Color       ansi.Mode     // when to color results
+	SavePath    string        // where caught Pokemon are kept between sessions, or "" to not keep them
+	Seed        int64         // seeds the catch rolls so sessions can be repeated, or 0 for a random seed
+}
+
+// The sources a setting's value can come from, as shown by "config show".
+const (
+	sourceDefault    = "default"
+	sourceConfigFile = "config file"
+)
+
+// settingSpec declares a setting: its name in the config file, and how its value is read from
+// and shown as text. Its environment variable and flag are named after it, e.g. "cache_ttl" is
+// $POKEDEX_CACHE_TTL and -cache-ttl.
+type settingSpec struct {
+	key         string
+	description string
+	set         func(s *settings, value string) error
+	get         func(s settings) string
+}
+
+func (spec settingSpec) env() string  { return "POKEDEX_" + strings.ToUpper(spec.key) }
+func (spec settingSpec) flag() string { return strings.ReplaceAll(spec.key, "_", "-") }
+
+// settingSpecs are all the settings, in the order "config show" lists them.
+var settingSpecs = []settingSpec{
+	{
+		key:         "api_url",
+		description: "root of the PokeAPI endpoints to use, e.g. http://localhost:8080/api/v2 for serve-mock",
+		set:         func(s *settings, v string) error { s.APIURL = v; return nil },
+		get:         func(s settings) string { return s.APIURL },
+	},
+	{
+		key:         "cache_ttl",
+		description: "how long API responses stay fresh in the cache, e.g. 90s or 10m",
+		set: func(s *settings, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("must be a positive duration like 90s or 10m, not %q", v)
+			}
+			s.CacheTTL = d
+			return nil
+		},
+		get: func(s settings) string { return s.CacheTTL.String() },
+	},
+	{
+		key:         "cache_size_mb",
+		description: "megabytes of API responses the cache keeps, after compression",
+		set: func(s *settings, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return fmt.Errorf("must be a positive whole number, not %q", v)
+			}
+			s.CacheSizeMB = n
+			return nil
+		},
+		get: func(s settings) string { return strconv.Itoa(s.CacheSizeMB) },
+	},
+	{
+		key:         "game_version",
+		description: "only show Pokemon found in this game, e.g. diamond (empty for every game)",
+		set:         func(s *settings, v string) error { s.GameVersion = strings.ToLower(v); return nil },
+		get:         func(s settings) string { return s.GameVersion },
+	},
+	{
+		key:         "output",
+		description: "how command results are shown: text, table, json, yaml or csv",
+		set: func(s *settings, v string) error {
+			f, err := output.ParseFormat(strings.ToLower(v))
+			if err != nil {
+				return err
+			}
+			s.Output = f
+			return nil
+		},
+		get: func(s settings) string { return string(s.Output) },
+	},
+	{
+		key:         "color",
+		description: "when to color the output: auto (when it goes to a terminal and $NO_COLOR isn't set), always or never",
+		set: func(s *settings, v string) error {
+			mode := ansi.Mode(strings.ToLower(v))
+			if !slices.Contains([]ansi.Mode{ansi.ModeAuto, ansi.ModeAlways, ansi.ModeNever}, mode) {
+				return fmt.Errorf("must be auto, always or never, not %q", v)
+			}
+			s.Color = mode
+			return nil
+		},
+		get: func(s settings) string { return string(s.Color) },
+	},
+	{
+		key:         "save_path",
+		description: "file your caught Pokemon are kept in between sessions, e.g. ~/.local/share/pokedex/pokedex.json. Nothing is kept unless it is set",
+		set:         func(s *settings, v string) error { s.SavePath = v; return nil },
+		get:         func(s settings) string { return s.SavePath },
+	},
+	{
+		key:         "seed",
+		description: "seed for the catch rolls, so a session can be repeated (0 for a random seed)",
+		set: func(s *settings, v string) error {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("must be a whole number, not %q", v)
+			}
+			s.Seed = n
+			return nil
+		},
+		get: func(s settings) string { return strconv.FormatInt(s.Seed, 10) },
+	},
+}
+
+// defaultSettings are the settings used when nothing else sets them.
+func defaultSettings() settings {
+	return settings{
+		APIURL:      pokeapi.DefaultBaseURL,
+		CacheTTL:    CACHE_LIFE_IN_SECONDS * time.Second,
+		CacheSizeMB: CACHE_MAX_BYTES >> 20,
+		Output:      output.FormatText,
+		Color:       ansi.ModeAuto,
+	}
+}
+
+// loadSettings works out the settings from the defaults, the config file at path (which doesn't
+// have to exist), environment variables found with lookupEnv and flags, the values given on
+// the command line keyed by setting. It also returns where each setting's value came from.
+func loadSettings(path string, lookupEnv func(string) (string, bool), flags map[string]string) (settings, map[string]string, error) {
+	s := defaultSettings()
+	sources := make(map[string]string)
+	for _, spec := range settingSpecs {
+		sources[spec.key] = sourceDefault
+	}
+
+	fileValues, err := readConfigFile(path)
+	if err != nil {
+		return settings{}, nil, err
+	}
+	for _, spec := range settingSpecs {
+		value, inFile := fileValues[spec.key]
+		source := sourceConfigFile
+		if v, ok := lookupEnv(spec.env()); ok {
+			value, source = v, "$"+spec.env()
+		}
+		if v, ok := flags[spec.key]; ok {
+			value, source = v, "-"+spec.flag()
+		}
+		if source == sourceConfigFile && !inFile {
+			continue
+		}
+		if err := spec.set(&s, value); err != nil {
+			return settings{}, nil, fmt.Errorf("error: %s from %s %w", spec.key, source, err)
+		}
+		sources[spec.key] = source
+	}
+	return s, sources, nil
+}
+
+// readConfigFile reads the JSON config file at path into the text form of each setting's
+// value. Settings can be given as JSON strings or numbers, e.g. {"cache_ttl": "90s", "seed": 42}.
+func readConfigFile(path string) (map[string]string, error) {
+	values := make(map[string]string)
+	if path == "" {
+		return values, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error: could not read config file: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("error: config file %s is not a JSON object: %w", path, err)
+	}
+	for key, value := range raw {
+		if !slices.ContainsFunc(settingSpecs, func(spec settingSpec) bool { return spec.key == key }) {
+			return nil, fmt.Errorf("error: config file %s has an unknown setting %q", path, key)
+		}
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			// not a string, so use the number as it is written
+			text = string(value)
+		}
+		values[key] = text
+	}
+	return values, nil
+}
+
+// defaultConfigFile is where the config file is read from unless -config says otherwise:
+// the pokedex directory in $XDG_CONFIG_HOME, or ~/.config if that isn't set.
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pokedex", "config.json")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/fuzzy"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokestore"
+)
+
+// maxSuggestions is how many "did you mean" suggestions are shown at most.
+const maxSuggestions = 3
+
+// suggestions returns the candidates word is most likely a typo of, quoted and joined with
+// "or", e.g. "'map' or 'mapb'". It returns "" when nothing is close enough.
+func suggestions(word string, candidates []string) string {
+	found := fuzzy.Suggest(word, candidates, maxSuggestions)
+	for i, s := range found {
+		found[i] = "'" + s + "'"
+	}
+	switch len(found) {
+	case 0:
+		return ""
+	case 1:
+		return found[0]
+	default:
+		return strings.Join(found[:len(found)-1], ", ") + " or " + found[len(found)-1]
+	}
+}
+
+// unknownCommandMessage explains that there is no command called name and suggests the
+// commands and aliases that might have been meant.
+func unknownCommandMessage(userConfig *config, name string) string {
+	if s := suggestions(name, userConfig.commandNames()); s != "" {
+		return fmt.Sprintf("Unknown command '%s'. Did you mean %s?", name, s)
+	}
+	return fmt.Sprintf("Unknown command '%s'. Run \"help\" to see available commands.", name)
+}
+
+// unknownPokemonError explains that there is no Pokemon called name, suggesting similarly named
+// species. The full species list comes from the API, so suggestions need it to be reachable,
+// but the Pokemon from the last explore are always considered.
+func unknownPokemonError(userConfig *config, name string) error {
+	candidates := append([]string{}, userConfig.LastEncounters...)
+	if species, err := userConfig.PokeClient.GetPokemonSpeciesNames(); err == nil {
+		candidates = append(candidates, species...)
+	}
+	if s := suggestions(name, candidates); s != "" {
+		return fmt.Errorf("No Pokemon '%s' — did you mean %s?", name, s)
+	}
+	return fmt.Errorf("No Pokemon '%s'", name)
+}
+
+// unknownAreaError explains that there is no location area called name, suggesting similarly
+// named areas from the API's list of every area and the map pages seen so far.
+func unknownAreaError(userConfig *config, name string) error {
+	candidates := append([]string{}, userConfig.SeenAreas...)
+	if areas, err := userConfig.PokeClient.GetLocationAreaNames(); err == nil {
+		candidates = append(candidates, areas...)
+	}
+	if s := suggestions(name, candidates); s != "" {
+		return fmt.Errorf("No location area '%s' — did you mean %s?", name, s)
+	}
+	return fmt.Errorf("No location area '%s'", name)
+}
+
+// isUnknownName reports whether err means there is no resource called name: either the API
+// doesn't know it or, offline, the synced list of every name doesn't include it.
+func isUnknownName(err error, name string, allNames func() ([]string, error)) bool {
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		return true
+	}
+	if !errors.Is(err, pokestore.ErrNotSynced) {
+		return false
+	}
+	names, err := allNames()
+	return err == nil && !slices.Contains(names, name)
+}
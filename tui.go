@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/pokeapi"
+	"github.com/rickNoise/15_build_a_pokedex_in_go/internal/tui"
+)
+
+// runTUI browses the Pokemon world in the full-screen interface instead of the repl, and
+// returns the exit code for the process. Pokemon caught in it go into the same Pokedex.
+func runTUI(userConfig *config, notes *tui.Notes) int {
+	err := tui.Run(os.Stdin, os.Stdout, tui.Options{
+		Client:      userConfig.PokeClient,
+		Cache:       userConfig.LocationCache,
+		Palette:     userConfig.Palette,
+		GameVersion: userConfig.Settings.GameVersion,
+		Notes:       notes,
+		Details: func(p pokeapi.Pokemon) string {
+			caught := caughtPokemon{Pokemon: p, Shiny: userConfig.Pokedex[p.Name].Shiny}
+			return newPokemonDetails(caught).ColorText(userConfig.Palette)
+		},
+		Caught: func(name string) bool {
+			_, ok := userConfig.Pokedex[name]
+			return ok
+		},
+		Catch: func(name string) string {
+			if _, ok := userConfig.Pokedex[name]; ok {
+				return fmt.Sprintf("you already have %s in your Pokedex", name)
+			}
+			attempt, err := throwPokeball(userConfig, name)
+			switch {
+			case err != nil:
+				return err.Error()
+			case attempt.Shiny:
+				return fmt.Sprintf("%s caught! It's shiny! %s", name, shinyMarker)
+			case attempt.Caught:
+				return fmt.Sprintf("%s caught! (rolled %d against %d)", name, attempt.Roll, attempt.BaseExperience)
+			}
+			return fmt.Sprintf("%s got away (rolled %d against %d)", name, attempt.Roll, attempt.BaseExperience)
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return EXIT_COMMAND_FAILED
+	}
+	return EXIT_OK
+}